@@ -0,0 +1,36 @@
+// Command auditverify checks that the hash chain of a phone book audit log is
+// unbroken, which detects records that have been modified, removed or
+// reordered.
+//
+// Usage:
+//
+//	auditverify <audit log file>
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/joshjon/go-phonebook/phonebook"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: auditverify <audit log file>")
+		os.Exit(2)
+	}
+
+	file, err := os.Open(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	count, err := phonebook.VerifyAuditLog(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit log is invalid after %d records: %v\n", count, err)
+		os.Exit(1)
+	}
+	fmt.Printf("audit log is valid: %d records\n", count)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strconv"
@@ -17,6 +18,9 @@ func main() {
 	contact, _ := book.Get(number)
 	fmt.Printf("- Got contact for '%s': %+v\n", number, contact)
 
+	contact, _ = book.GetByID(contact.ID)
+	fmt.Printf("- Got contact for ID '%s': %+v\n", contact.ID, contact)
+
 	prefix := "0410000"
 	contacts := book.FindByPrefix(prefix)
 	fmt.Printf("- Found %d contacts for prefix '%s'\n", len(contacts), prefix)
@@ -40,9 +44,12 @@ func main() {
 
 func loadDummyContacts(pb *phonebook.PhoneBook) {
 	rand.Seed(time.Now().UnixNano())
-	for i := 0; i < 200000; i++ {
+	contacts := make([]phonebook.Contact, 200000)
+	for i := range contacts {
 		contact := phonebook.Contact{}
-		contact.Number = "04" + strconv.Itoa(10000000+i)
+		contact.Numbers = []phonebook.PhoneNumber{
+			{Type: phonebook.Mobile, Number: "04" + strconv.Itoa(10000000+i)},
+		}
 
 		num := i % 10000
 		contact.FirstName = "firstname" + strconv.Itoa(num)
@@ -52,9 +59,12 @@ func loadDummyContacts(pb *phonebook.PhoneBook) {
 			city := "city" + strconv.Itoa(num)
 			contact.Address = fmt.Sprintf("1 foo st, %s, foo state, 1111, foo country", city)
 		}
+		contacts[i] = contact
+	}
 
-		if err := pb.Add(contact); err != nil {
-			panic(err)
+	for _, result := range pb.AddMany(context.Background(), contacts, phonebook.StopOnError) {
+		if result.Err != nil {
+			panic(result.Err)
 		}
 	}
 }
@@ -0,0 +1,68 @@
+package index
+
+// Batch holds items to add to Indexes together, which is faster than adding them
+// one at a time. Items are checked against both the indexes and the items
+// already in the batch, so that the batch never violates a constraint.
+type Batch[T comparable] struct {
+	indexes *Indexes[T]
+	items   []T
+	// keys holds the keys of the items in the batch by the name of each index
+	// that is a Checker.
+	keys map[string]map[string]T
+}
+
+// keyChecker is a Checker that can report the keys an item is checked under.
+type keyChecker[T comparable] interface {
+	Checker[T]
+	keys(item T) []string
+}
+
+// NewBatch returns a new Batch of items to add to the indexes.
+func (i *Indexes[T]) NewBatch() *Batch[T] {
+	return &Batch[T]{indexes: i, keys: map[string]map[string]T{}}
+}
+
+// Check returns an error if the item can not be added to the indexes alongside
+// the items in the batch.
+func (b *Batch[T]) Check(item T) error {
+	var none T
+	if err := b.indexes.Check(none, item); err != nil {
+		return err
+	}
+	for _, name := range b.indexes.names {
+		checker, ok := b.indexes.indexes[name].(keyChecker[T])
+		if !ok {
+			continue
+		}
+		for _, key := range checker.keys(item) {
+			if existing, ok := b.keys[name][key]; ok {
+				return &ConflictError[T]{Index: name, Key: key, Existing: existing}
+			}
+		}
+	}
+	return nil
+}
+
+// Add adds the item to the batch. The item must have been checked with Check.
+func (b *Batch[T]) Add(item T) {
+	b.items = append(b.items, item)
+	for _, name := range b.indexes.names {
+		checker, ok := b.indexes.indexes[name].(keyChecker[T])
+		if !ok {
+			continue
+		}
+		if b.keys[name] == nil {
+			b.keys[name] = map[string]T{}
+		}
+		for _, key := range checker.keys(item) {
+			b.keys[name][key] = item
+		}
+	}
+}
+
+// Commit adds the items in the batch to every index.
+func (b *Batch[T]) Commit() {
+	b.indexes.AddAll(b.items)
+	b.items = nil
+	b.keys = map[string]map[string]T{}
+}
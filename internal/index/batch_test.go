@@ -0,0 +1,44 @@
+package index
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	unique := NewUniqueIndex[*bar]("unique", func(bar *bar) []string { return bar.tags })
+	ordered := NewOrderedIndex[*bar]("ordered", func(bar *bar) []string { return []string{bar.name} })
+	indexes := NewIndexes[*bar](unique, ordered)
+	existing := &bar{name: "existing", tags: []string{"lorem"}}
+	indexes.Add(existing)
+
+	batch := indexes.NewBatch()
+	want1 := &bar{name: "one", tags: []string{"ipsum"}}
+	require.NoError(t, batch.Check(want1))
+	batch.Add(want1)
+
+	// Items conflict with both the indexes and the items in the batch
+	var conflictErr *ConflictError[*bar]
+	err := batch.Check(&bar{name: "two", tags: []string{"lorem"}})
+	require.True(t, errors.As(err, &conflictErr))
+	require.Equal(t, existing, conflictErr.Existing)
+	err = batch.Check(&bar{name: "two", tags: []string{"ipsum"}})
+	require.True(t, errors.As(err, &conflictErr))
+	require.Equal(t, want1, conflictErr.Existing)
+
+	want2 := &bar{name: "two", tags: []string{"dolor"}}
+	require.NoError(t, batch.Check(want2))
+	batch.Add(want2)
+
+	// Nothing is indexed until the batch is committed
+	_, ok := indexes.Get("unique", "ipsum")
+	require.False(t, ok)
+
+	batch.Commit()
+	items, ok := indexes.Get("unique", "ipsum")
+	require.True(t, ok)
+	require.Equal(t, []*bar{want1}, items)
+	require.Equal(t, []*bar{existing, want1, want2}, ordered.Range("", "", RangeOptions{}))
+}
@@ -1,11 +1,15 @@
 package index
 
-import "github.com/deckarep/golang-set/v2"
+import (
+	"fmt"
+
+	"github.com/deckarep/golang-set/v2"
+)
 
 // MapIndex uses a map to index records on one or more fields, which makes
 // searching dramatically faster for the specified field(s) at O(1) time complexity.
 type MapIndex[T comparable] struct {
-	id    int
+	name  string
 	index map[string]mapset.Set[T]
 	// A function to specify which fields should be returned from the item for
 	// the index. For multiple fields simply concatenating them will suffice.
@@ -15,9 +19,9 @@ type MapIndex[T comparable] struct {
 }
 
 // NewMapIndex returns a new MapIndex.
-func NewMapIndex[T comparable](id int, keyFn func(T) (string, bool)) *MapIndex[T] {
+func NewMapIndex[T comparable](name string, keyFn func(T) (string, bool)) *MapIndex[T] {
 	return &MapIndex[T]{
-		id:    id,
+		name:  name,
 		index: map[string]mapset.Set[T]{},
 		keyFn: keyFn,
 	}
@@ -34,6 +38,25 @@ func (i *MapIndex[T]) Add(item T) {
 	}
 }
 
+// AddAll adds the items to the map index. The items are grouped by key first, so
+// that the set of each key is looked up or created once rather than per item.
+func (i *MapIndex[T]) AddAll(items []T) {
+	groups := map[string][]T{}
+	for _, item := range items {
+		if key, ok := i.keyFn(item); ok {
+			groups[key] = append(groups[key], item)
+		}
+	}
+	for key, group := range groups {
+		addToSet(i.index, key, group)
+	}
+}
+
+// Name returns the name of the index.
+func (i *MapIndex[T]) Name() string {
+	return i.name
+}
+
 // Get returns the item for the specified key.
 func (i *MapIndex[T]) Get(key string) ([]T, bool) {
 	items, ok := i.index[key]
@@ -44,6 +67,12 @@ func (i *MapIndex[T]) Get(key string) ([]T, bool) {
 	return items.ToSlice(), true
 }
 
+// Update replaces the old item with the new item.
+func (i *MapIndex[T]) Update(old T, new T) {
+	i.Delete(old)
+	i.Add(new)
+}
+
 // Delete removes the specified item from the index.
 func (i *MapIndex[T]) Delete(item T) {
 	if key, ok := i.keyFn(item); ok {
@@ -56,38 +85,131 @@ func (i *MapIndex[T]) Delete(item T) {
 	}
 }
 
-// Indexes holds multiple indexes to easily perform operations across.
+// Index is an index of items that can be held by Indexes.
+type Index[T comparable] interface {
+	Name() string
+	Add(item T)
+	Update(old T, new T)
+	Get(key string) ([]T, bool)
+	Delete(item T)
+}
+
+// Indexes holds multiple indexes by name to easily perform operations across.
 type Indexes[T comparable] struct {
-	indexes []*MapIndex[T]
+	indexes map[string]Index[T]
+	// names holds the name of each index in the order they were added. Every
+	// operation across indexes visits them in this order, so that it is
+	// deterministic.
+	names []string
 }
 
 // NewIndexes returns a new Indexes that contains the provided indexes.
-func NewIndexes[T comparable](indexes ...*MapIndex[T]) *Indexes[T] {
-	return &Indexes[T]{
-		indexes: indexes,
+func NewIndexes[T comparable](indexes ...Index[T]) *Indexes[T] {
+	i := &Indexes[T]{
+		indexes: map[string]Index[T]{},
+	}
+	for _, index := range indexes {
+		i.indexes[index.Name()] = index
+		i.names = append(i.names, index.Name())
+	}
+	return i
+}
+
+// Register adds a new index and builds it from the provided items, which should
+// be every item held by the other indexes. Index names must be unique. If the
+// index is a Checker, an error is returned if any item violates its constraint.
+func (i *Indexes[T]) Register(index Index[T], items []T) error {
+	if _, ok := i.indexes[index.Name()]; ok {
+		return fmt.Errorf("index %s already exists", index.Name())
+	}
+
+	checker, _ := index.(Checker[T])
+	var none T
+	for _, item := range items {
+		if checker != nil {
+			if err := checker.Check(none, item); err != nil {
+				return err
+			}
+		}
+		index.Add(item)
 	}
+	i.indexes[index.Name()] = index
+	i.names = append(i.names, index.Name())
+	return nil
+}
+
+// Has reports whether an index with the specified name exists.
+func (i *Indexes[T]) Has(name string) bool {
+	_, ok := i.indexes[name]
+	return ok
+}
+
+// Check returns an error if the old item can not be replaced by the new item in
+// any index that is a Checker. Pass the zero value of T as the old item to check
+// a new item. Nothing is modified, so a failed check leaves the indexes intact.
+func (i *Indexes[T]) Check(old T, new T) error {
+	for _, name := range i.names {
+		if checker, ok := i.indexes[name].(Checker[T]); ok {
+			if err := checker.Check(old, new); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // Add adds the item to each index.
 func (i *Indexes[T]) Add(item T) {
-	for _, index := range i.indexes {
-		index.Add(item)
+	for _, name := range i.names {
+		i.indexes[name].Add(item)
 	}
 }
 
-// Get returns all items from the specified index for the provided key.
-func (i Indexes[T]) Get(id int, key string) ([]T, bool) {
-	for _, index := range i.indexes {
-		if index.id == id {
-			return index.Get(key)
+// AddAll adds the items to each index. Indexes that implement AddAll are built
+// from all of the items at once, rather than one item at a time.
+func (i *Indexes[T]) AddAll(items []T) {
+	for _, name := range i.names {
+		index := i.indexes[name]
+		if bulk, ok := index.(interface{ AddAll(items []T) }); ok {
+			bulk.AddAll(items)
+			continue
+		}
+		for _, item := range items {
+			index.Add(item)
 		}
 	}
+}
+
+// Update replaces the old item with the new item in each index.
+func (i *Indexes[T]) Update(old T, new T) {
+	for _, name := range i.names {
+		i.indexes[name].Update(old, new)
+	}
+}
+
+// Get returns all items from the specified index for the provided key.
+func (i *Indexes[T]) Get(name string, key string) ([]T, bool) {
+	if index, ok := i.indexes[name]; ok {
+		return index.Get(key)
+	}
 	return nil, false
 }
 
 // Delete removes the specified item from each index.
-func (i Indexes[T]) Delete(item T) {
-	for _, index := range i.indexes {
-		index.Delete(item)
+func (i *Indexes[T]) Delete(item T) {
+	for _, name := range i.names {
+		i.indexes[name].Delete(item)
+	}
+}
+
+// addToSet adds the items to the set of the key, creating the set if the key
+// has none.
+func addToSet[T comparable](index map[string]mapset.Set[T], key string, items []T) {
+	if set, ok := index[key]; ok {
+		for _, item := range items {
+			set.Add(item)
+		}
+		return
 	}
+	index[key] = mapset.NewSet[T](items...)
 }
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,7 +13,7 @@ type foo struct {
 }
 
 func TestIndexes(t *testing.T) {
-	loremIndex, ipsumIndex := 1, 2
+	loremIndex, ipsumIndex := "lorem", "ipsum"
 
 	// Create lorem and ipsum indexes
 	indexes := NewIndexes[foo](
@@ -49,3 +50,130 @@ func TestIndexes(t *testing.T) {
 	require.False(t, ok)
 	require.Empty(t, items)
 }
+
+func TestMapIndex_AddAll(t *testing.T) {
+	keyFn := func(foo foo) (string, bool) { return foo.lorem, foo.lorem != "" }
+	bulk := NewMapIndex[foo]("lorem", keyFn)
+	single := NewMapIndex[foo]("lorem", keyFn)
+	existing := foo{lorem: "lorem1", ipsum: "existing"}
+	bulk.Add(existing)
+	single.Add(existing)
+
+	var items []foo
+	for i := 0; i < 100; i++ {
+		items = append(items, foo{lorem: fmt.Sprintf("lorem%d", i%7), ipsum: fmt.Sprint(i)})
+	}
+	items = append(items, foo{ipsum: "skipped"})
+	bulk.AddAll(items)
+	for _, item := range items {
+		single.Add(item)
+	}
+
+	// Building in bulk gives the same index as adding one item at a time
+	require.Len(t, bulk.index, len(single.index))
+	for key := range single.index {
+		want, _ := single.Get(key)
+		got, ok := bulk.Get(key)
+		require.True(t, ok)
+		require.ElementsMatch(t, want, got)
+	}
+	found, ok := bulk.Get("lorem1")
+	require.True(t, ok)
+	require.Contains(t, found, existing)
+}
+
+func TestIndexes_Update(t *testing.T) {
+	loremIndex, tagsIndex := "lorem", "tags"
+
+	indexes := NewIndexes[*bar](
+		NewMapIndex[*bar](loremIndex, func(bar *bar) (string, bool) { return bar.name, true }),
+		NewMultiMapIndex[*bar](tagsIndex, func(bar *bar) []string { return bar.tags }),
+	)
+
+	old := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	new := &bar{name: "two", tags: []string{"ipsum"}}
+	indexes.Add(old)
+	indexes.Update(old, new)
+
+	_, ok := indexes.Get(loremIndex, "one")
+	require.False(t, ok)
+	items, ok := indexes.Get(loremIndex, "two")
+	require.True(t, ok)
+	require.Equal(t, []*bar{new}, items)
+
+	_, ok = indexes.Get(tagsIndex, "lorem")
+	require.False(t, ok)
+	items, ok = indexes.Get(tagsIndex, "ipsum")
+	require.True(t, ok)
+	require.Equal(t, []*bar{new}, items)
+}
+
+func TestIndexes_Register(t *testing.T) {
+	indexes := NewIndexes[foo](
+		NewMapIndex[foo]("lorem", func(foo foo) (string, bool) { return foo.lorem, true }),
+	)
+
+	want1 := foo{lorem: "lorem1", ipsum: "ipsum1"}
+	want2 := foo{lorem: "lorem2", ipsum: "ipsum1"}
+	indexes.Add(want1)
+	indexes.Add(want2)
+
+	// Registering an index builds it from the existing items
+	ipsumIndex := NewMapIndex[foo]("ipsum", func(foo foo) (string, bool) { return foo.ipsum, true })
+	require.NoError(t, indexes.Register(ipsumIndex, []foo{want1, want2}))
+	require.True(t, indexes.Has("ipsum"))
+	items, ok := indexes.Get("ipsum", "ipsum1")
+	require.True(t, ok)
+	require.ElementsMatch(t, []foo{want1, want2}, items)
+
+	// Registered indexes are kept up to date
+	want3 := foo{lorem: "lorem3", ipsum: "ipsum1"}
+	indexes.Add(want3)
+	items, ok = indexes.Get("ipsum", "ipsum1")
+	require.True(t, ok)
+	require.ElementsMatch(t, []foo{want1, want2, want3}, items)
+
+	err := indexes.Register(NewMapIndex[foo]("lorem", func(foo foo) (string, bool) { return foo.lorem, true }), nil)
+	require.EqualError(t, err, "index lorem already exists")
+}
+
+func TestIndexes_order(t *testing.T) {
+	var calls []string
+	names := []string{"c", "a", "d", "b", "e"}
+	var all []Index[foo]
+	for _, name := range names {
+		all = append(all, &recordingIndex{name: name, calls: &calls})
+	}
+	indexes := NewIndexes[foo](all...)
+	require.NoError(t, indexes.Register(&recordingIndex{name: "f", calls: &calls}, nil))
+	names = append(names, "f")
+
+	// Every operation visits the indexes in the order they were added
+	for i := 0; i < 10; i++ {
+		calls = nil
+		indexes.Add(foo{})
+		indexes.AddAll([]foo{{}})
+		indexes.Update(foo{}, foo{})
+		indexes.Delete(foo{})
+
+		var want []string
+		for _, op := range []string{"add", "add", "update", "delete"} {
+			for _, name := range names {
+				want = append(want, op+":"+name)
+			}
+		}
+		require.Equal(t, want, calls)
+	}
+}
+
+// recordingIndex is an Index that records the operations applied to it.
+type recordingIndex struct {
+	name  string
+	calls *[]string
+}
+
+func (i *recordingIndex) Name() string             { return i.name }
+func (i *recordingIndex) Add(foo)                  { *i.calls = append(*i.calls, "add:"+i.name) }
+func (i *recordingIndex) Update(foo, foo)          { *i.calls = append(*i.calls, "update:"+i.name) }
+func (i *recordingIndex) Get(string) ([]foo, bool) { return nil, false }
+func (i *recordingIndex) Delete(foo)               { *i.calls = append(*i.calls, "delete:"+i.name) }
@@ -0,0 +1,141 @@
+package index
+
+import "github.com/deckarep/golang-set/v2"
+
+// MultiMapIndex is a map index that can index each item under multiple keys,
+// which is useful for fields that hold multiple values (e.g. tags, email
+// addresses or the words of a name).
+type MultiMapIndex[T comparable] struct {
+	name  string
+	index map[string]mapset.Set[T]
+	// itemKeys holds the keys each item is indexed under, so that an item is
+	// removed from the correct keys even if its key set has since changed.
+	itemKeys map[T][]string
+	// A function to specify the keys the item should be indexed under. Return
+	// an empty slice to skip the item. Duplicate keys are ignored.
+	keysFn func(T) []string
+}
+
+// NewMultiMapIndex returns a new MultiMapIndex.
+func NewMultiMapIndex[T comparable](name string, keysFn func(T) []string) *MultiMapIndex[T] {
+	return &MultiMapIndex[T]{
+		name:     name,
+		index:    map[string]mapset.Set[T]{},
+		itemKeys: map[T][]string{},
+		keysFn:   keysFn,
+	}
+}
+
+// Name returns the name of the index.
+func (i *MultiMapIndex[T]) Name() string {
+	return i.name
+}
+
+// Add adds a new item to the index under each of its keys. Adding an item that
+// is already indexed re-indexes it under its current keys.
+func (i *MultiMapIndex[T]) Add(item T) {
+	i.Update(item, item)
+}
+
+// AddAll adds the items to the index under each of their keys. The items are
+// grouped by key first, so that the set of each key is looked up or created
+// once rather than per item. Items that are already indexed are re-indexed as
+// by Add.
+func (i *MultiMapIndex[T]) AddAll(items []T) {
+	groups := map[string][]T{}
+	for _, item := range items {
+		if _, ok := i.itemKeys[item]; ok {
+			i.Add(item)
+			continue
+		}
+		keys := mapset.NewSet[string](i.keysFn(item)...).ToSlice()
+		if len(keys) == 0 {
+			continue
+		}
+		for _, key := range keys {
+			groups[key] = append(groups[key], item)
+		}
+		i.itemKeys[item] = keys
+	}
+	for key, group := range groups {
+		addToSet(i.index, key, group)
+	}
+}
+
+// Update replaces the old item with the new item. The item is removed from the
+// keys that only the old item has and added to the keys that only the new item
+// has, while under the keys they share the new item replaces the old item in
+// place, so those keys are never removed and re-added.
+func (i *MultiMapIndex[T]) Update(old T, new T) {
+	if _, ok := i.itemKeys[new]; ok && old != new {
+		i.Delete(new)
+	}
+
+	oldKeys := mapset.NewSet[string](i.itemKeys[old]...)
+	newKeys := mapset.NewSet[string](i.keysFn(new)...)
+	delete(i.itemKeys, old)
+
+	for _, key := range oldKeys.ToSlice() {
+		if !newKeys.Contains(key) {
+			i.remove(key, old)
+		} else if items, ok := i.index[key]; ok && old != new {
+			items.Remove(old)
+			items.Add(new)
+		}
+	}
+
+	for _, key := range newKeys.Difference(oldKeys).ToSlice() {
+		if items, ok := i.index[key]; ok {
+			items.Add(new)
+		} else {
+			i.index[key] = mapset.NewSet[T](new)
+		}
+	}
+	if newKeys.Cardinality() > 0 {
+		i.itemKeys[new] = newKeys.ToSlice()
+	}
+}
+
+// Get returns the items for the specified key.
+func (i *MultiMapIndex[T]) Get(key string) ([]T, bool) {
+	items, ok := i.index[key]
+	if !ok {
+		return nil, false
+	}
+
+	return items.ToSlice(), true
+}
+
+// Keys returns every key that has at least one item.
+func (i *MultiMapIndex[T]) Keys() []string {
+	keys := make([]string, 0, len(i.index))
+	for key := range i.index {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Count returns the number of items for the specified key.
+func (i *MultiMapIndex[T]) Count(key string) int {
+	if items, ok := i.index[key]; ok {
+		return items.Cardinality()
+	}
+	return 0
+}
+
+// Delete removes the specified item from each of the keys it is indexed under.
+func (i *MultiMapIndex[T]) Delete(item T) {
+	for _, key := range i.itemKeys[item] {
+		i.remove(key, item)
+	}
+	delete(i.itemKeys, item)
+}
+
+func (i *MultiMapIndex[T]) remove(key string, item T) {
+	if items, ok := i.index[key]; ok {
+		items.Remove(item)
+		if items.Cardinality() == 0 {
+			delete(i.index, key)
+		}
+	}
+}
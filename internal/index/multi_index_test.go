@@ -0,0 +1,166 @@
+package index
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bar struct {
+	name string
+	tags []string
+}
+
+func TestMultiMapIndex(t *testing.T) {
+	index := NewMultiMapIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+
+	want1 := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	want2 := &bar{name: "two", tags: []string{"lorem", "lorem"}}
+	want3 := &bar{name: "three"}
+	index.Add(want1)
+	index.Add(want2)
+	index.Add(want3)
+
+	items, ok := index.Get("lorem")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{want1, want2}, items)
+	items, ok = index.Get("ipsum")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{want1}, items)
+	require.ElementsMatch(t, []string{"lorem", "ipsum"}, index.Keys())
+	require.Equal(t, 2, index.Count("lorem"))
+	require.Equal(t, 0, index.Count("dolor"))
+
+	index.Delete(want1)
+	items, ok = index.Get("lorem")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{want2}, items)
+	_, ok = index.Get("ipsum")
+	require.False(t, ok)
+
+	index.Delete(want2)
+	index.Delete(want3)
+	require.Empty(t, index.index)
+}
+
+func TestMultiMapIndex_AddAll(t *testing.T) {
+	keysFn := func(bar *bar) []string { return bar.tags }
+	bulk := NewMultiMapIndex[*bar]("tags", keysFn)
+	single := NewMultiMapIndex[*bar]("tags", keysFn)
+	existing := &bar{name: "existing", tags: []string{"key1"}}
+	bulk.Add(existing)
+	single.Add(existing)
+
+	var items []*bar
+	for i := 0; i < 100; i++ {
+		items = append(items, &bar{name: fmt.Sprint(i), tags: []string{fmt.Sprintf("key%d", i%7), "shared", "shared"}})
+	}
+	// An item that is already indexed is re-indexed under its current keys
+	existing.tags = []string{"key2"}
+	items = append(items, &bar{name: "untagged"}, existing)
+	bulk.AddAll(items)
+	for _, item := range items {
+		single.Add(item)
+	}
+
+	// Building in bulk gives the same index as adding one item at a time
+	require.ElementsMatch(t, single.Keys(), bulk.Keys())
+	for _, key := range single.Keys() {
+		want, _ := single.Get(key)
+		got, ok := bulk.Get(key)
+		require.True(t, ok)
+		require.ElementsMatch(t, want, got)
+	}
+	require.Equal(t, 100, bulk.Count("shared"))
+	require.Len(t, bulk.itemKeys, len(single.itemKeys))
+	for item, keys := range single.itemKeys {
+		require.ElementsMatch(t, keys, bulk.itemKeys[item])
+	}
+}
+
+func TestMultiMapIndex_Update(t *testing.T) {
+	index := NewMultiMapIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+
+	old := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	other := &bar{name: "two", tags: []string{"lorem", "dolor"}}
+	index.Add(old)
+	index.Add(other)
+
+	// Replace ipsum with dolor, keeping the overlapping lorem key
+	new := &bar{name: "one", tags: []string{"lorem", "dolor"}}
+	index.Update(old, new)
+
+	items, ok := index.Get("lorem")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{new, other}, items)
+	items, ok = index.Get("dolor")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{new, other}, items)
+	_, ok = index.Get("ipsum")
+	require.False(t, ok)
+
+	index.Delete(new)
+	items, ok = index.Get("lorem")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{other}, items)
+	items, ok = index.Get("dolor")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{other}, items)
+
+	index.Delete(other)
+	require.Empty(t, index.index)
+	require.Empty(t, index.itemKeys)
+}
+
+func TestMultiMapIndex_Update_sharedKeys(t *testing.T) {
+	index := NewMultiMapIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+	old := &bar{name: "one", tags: []string{"lorem"}}
+	index.Add(old)
+	lorem := index.index["lorem"]
+
+	// The new item replaces the old item under a shared key, without removing
+	// the key
+	new := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	index.Update(old, new)
+	require.True(t, lorem == index.index["lorem"])
+	items, ok := index.Get("lorem")
+	require.True(t, ok)
+	require.Equal(t, []*bar{new}, items)
+	require.ElementsMatch(t, []string{"lorem", "ipsum"}, index.itemKeys[new])
+	_, ok = index.itemKeys[old]
+	require.False(t, ok)
+}
+
+func TestMultiMapIndex_changedKeys(t *testing.T) {
+	index := NewMultiMapIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+
+	item := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	other := &bar{name: "two", tags: []string{"ipsum"}}
+	index.Add(item)
+	index.Add(other)
+
+	// Re-adding an item after its keys change only moves it between the keys
+	// that differ
+	item.tags = []string{"ipsum", "dolor"}
+	index.Add(item)
+
+	_, ok := index.Get("lorem")
+	require.False(t, ok)
+	items, ok := index.Get("ipsum")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{item, other}, items)
+	items, ok = index.Get("dolor")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{item}, items)
+
+	// Deleting an item whose keys have changed since it was indexed removes it
+	// from the keys it was indexed under
+	item.tags = nil
+	index.Delete(item)
+	_, ok = index.Get("dolor")
+	require.False(t, ok)
+	items, ok = index.Get("ipsum")
+	require.True(t, ok)
+	require.ElementsMatch(t, []*bar{other}, items)
+}
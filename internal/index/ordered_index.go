@@ -0,0 +1,269 @@
+package index
+
+import (
+	"math/rand"
+	"sort"
+)
+
+const (
+	maxLevel = 32
+	// levelProbability is the probability of a node being promoted to the next
+	// level of the skip list.
+	levelProbability = 0.25
+)
+
+type skipNode[T comparable] struct {
+	key string
+	// items holds the items with the key in the order they were added.
+	items []T
+	next  []*skipNode[T]
+}
+
+// RangeOptions configures a range scan of an OrderedIndex.
+type RangeOptions struct {
+	// Limit is the maximum number of items returned. Zero means no limit.
+	Limit int
+	// Descending returns items in descending key order.
+	Descending bool
+}
+
+// OrderedIndex uses a skip list to index records in key order, which supports
+// exact matches and finding the start of a range scan in O(log n) time
+// complexity, as well as iterating items in key order. Like a MultiMapIndex, an
+// item can be indexed under multiple keys.
+type OrderedIndex[T comparable] struct {
+	name  string
+	head  *skipNode[T]
+	level int
+	rand  *rand.Rand
+	// itemKeys holds the keys each item is indexed under.
+	itemKeys map[T][]string
+	// A function to specify the keys the item should be indexed under. Keys are
+	// ordered lexicographically, so numeric fields should have a fixed width.
+	// Return an empty slice to skip the item. Duplicate keys are ignored.
+	keysFn func(T) []string
+}
+
+// NewOrderedIndex returns a new OrderedIndex.
+func NewOrderedIndex[T comparable](name string, keysFn func(T) []string) *OrderedIndex[T] {
+	return &OrderedIndex[T]{
+		name:     name,
+		head:     &skipNode[T]{next: make([]*skipNode[T], maxLevel)},
+		level:    1,
+		rand:     rand.New(rand.NewSource(1)),
+		itemKeys: map[T][]string{},
+		keysFn:   keysFn,
+	}
+}
+
+// Name returns the name of the index.
+func (i *OrderedIndex[T]) Name() string {
+	return i.name
+}
+
+// Add adds a new item to the index under each of its keys.
+func (i *OrderedIndex[T]) Add(item T) {
+	seen := map[string]bool{}
+	for _, key := range i.keysFn(item) {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		i.insert(key, item, nil)
+		i.itemKeys[item] = append(i.itemKeys[item], key)
+	}
+}
+
+// AddAll adds the items to the index. The items' keys are sorted and inserted in
+// order, so that each insert continues from the position of the previous one
+// rather than searching from the head of the skip list.
+func (i *OrderedIndex[T]) AddAll(items []T) {
+	type entry struct {
+		key  string
+		item T
+	}
+	var entries []entry
+	for _, item := range items {
+		seen := map[string]bool{}
+		for _, key := range i.keysFn(item) {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			entries = append(entries, entry{key: key, item: item})
+			i.itemKeys[item] = append(i.itemKeys[item], key)
+		}
+	}
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].key < entries[b].key })
+
+	var from *[maxLevel]*skipNode[T]
+	for _, e := range entries {
+		update := i.insert(e.key, e.item, from)
+		from = &update
+	}
+}
+
+// Update replaces the old item with the new item.
+func (i *OrderedIndex[T]) Update(old T, new T) {
+	i.Delete(old)
+	i.Add(new)
+}
+
+// Get returns the items for the specified key.
+func (i *OrderedIndex[T]) Get(key string) ([]T, bool) {
+	_, node := i.seek(key)
+	if node == nil || node.key != key {
+		return nil, false
+	}
+	return append([]T(nil), node.items...), true
+}
+
+// Range returns the items with a key between lo and hi inclusive, in key order.
+// An empty bound is unbounded, so an empty lo and hi returns every item. Items
+// indexed under several keys in the range are only returned once, at the
+// position of their first key in the requested order.
+func (i *OrderedIndex[T]) Range(lo string, hi string, opts RangeOptions) []T {
+	items := []T{}
+	seen := map[T]bool{}
+	collect := func(item T) bool {
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+		return opts.Limit <= 0 || len(items) < opts.Limit
+	}
+
+	if !opts.Descending {
+		i.scan(lo, hi, func(_ string, item T) bool { return collect(item) })
+		return items
+	}
+
+	// Descending scans must reach the end of the range before any items can be
+	// returned, since the skip list is only linked forwards
+	var matched []T
+	i.scan(lo, hi, func(_ string, item T) bool {
+		matched = append(matched, item)
+		return true
+	})
+	for idx := len(matched) - 1; idx >= 0; idx-- {
+		if !collect(matched[idx]) {
+			break
+		}
+	}
+	return items
+}
+
+// Ascend calls fn for each key and item in ascending key order until fn returns
+// false. Items with the same key are visited in the order they were added.
+func (i *OrderedIndex[T]) Ascend(fn func(key string, item T) bool) {
+	i.scan("", "", fn)
+}
+
+// Delete removes the specified item from each of the keys it is indexed under.
+func (i *OrderedIndex[T]) Delete(item T) {
+	for _, key := range i.itemKeys[item] {
+		i.remove(key, item)
+	}
+	delete(i.itemKeys, item)
+}
+
+// insert adds the item to the node for the key, creating the node if it does
+// not exist, and returns the last node before the key at each level. The search
+// for the key starts from the nodes in from, if provided (see seek).
+func (i *OrderedIndex[T]) insert(key string, item T, from *[maxLevel]*skipNode[T]) [maxLevel]*skipNode[T] {
+	update, node := i.seekFrom(key, from)
+	if node != nil && node.key == key {
+		node.items = append(node.items, item)
+		return update
+	}
+
+	level := i.randomLevel()
+	if level > i.level {
+		for l := i.level; l < level; l++ {
+			update[l] = i.head
+		}
+		i.level = level
+	}
+
+	node = &skipNode[T]{key: key, items: []T{item}, next: make([]*skipNode[T], level)}
+	for l := 0; l < level; l++ {
+		node.next[l] = update[l].next[l]
+		update[l].next[l] = node
+	}
+	return update
+}
+
+// remove removes the item from the node for the key, and removes the node once
+// it has no items.
+func (i *OrderedIndex[T]) remove(key string, item T) {
+	update, node := i.seek(key)
+	if node == nil || node.key != key {
+		return
+	}
+
+	for idx, existing := range node.items {
+		if existing == item {
+			node.items = append(node.items[:idx], node.items[idx+1:]...)
+			break
+		}
+	}
+	if len(node.items) > 0 {
+		return
+	}
+
+	for l := 0; l < i.level; l++ {
+		if update[l].next[l] != node {
+			break
+		}
+		update[l].next[l] = node.next[l]
+	}
+	for i.level > 1 && i.head.next[i.level-1] == nil {
+		i.level--
+	}
+}
+
+// seek returns the last node before the key at each level, and the first node
+// with a key greater than or equal to the key.
+func (i *OrderedIndex[T]) seek(key string) ([maxLevel]*skipNode[T], *skipNode[T]) {
+	return i.seekFrom(key, nil)
+}
+
+// seekFrom is like seek, but at each level the search starts from the node in
+// from if it is further along than the node reached by the level above. Each
+// node in from must precede the key, such as the nodes returned by the insert of
+// a smaller key.
+func (i *OrderedIndex[T]) seekFrom(key string, from *[maxLevel]*skipNode[T]) ([maxLevel]*skipNode[T], *skipNode[T]) {
+	var update [maxLevel]*skipNode[T]
+	current := i.head
+	for l := i.level - 1; l >= 0; l-- {
+		if from != nil && from[l] != nil && from[l] != i.head && (current == i.head || from[l].key > current.key) {
+			current = from[l]
+		}
+		for current.next[l] != nil && current.next[l].key < key {
+			current = current.next[l]
+		}
+		update[l] = current
+	}
+	return update, current.next[0]
+}
+
+// scan calls fn for each item with a key between lo and hi inclusive in
+// ascending key order until fn returns false.
+func (i *OrderedIndex[T]) scan(lo string, hi string, fn func(key string, item T) bool) {
+	_, node := i.seek(lo)
+	for ; node != nil && (hi == "" || node.key <= hi); node = node.next[0] {
+		for _, item := range node.items {
+			if !fn(node.key, item) {
+				return
+			}
+		}
+	}
+}
+
+func (i *OrderedIndex[T]) randomLevel() int {
+	level := 1
+	for level < maxLevel && i.rand.Float64() < levelProbability {
+		level++
+	}
+	return level
+}
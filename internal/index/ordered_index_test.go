@@ -0,0 +1,187 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedIndex(t *testing.T) {
+	index := NewOrderedIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+
+	want1 := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	want2 := &bar{name: "two", tags: []string{"lorem", "lorem"}}
+	want3 := &bar{name: "three"}
+	index.Add(want1)
+	index.Add(want2)
+	index.Add(want3)
+
+	items, ok := index.Get("lorem")
+	require.True(t, ok)
+	require.Equal(t, []*bar{want1, want2}, items)
+	items, ok = index.Get("ipsum")
+	require.True(t, ok)
+	require.Equal(t, []*bar{want1}, items)
+	_, ok = index.Get("dolor")
+	require.False(t, ok)
+
+	index.Delete(want1)
+	items, ok = index.Get("lorem")
+	require.True(t, ok)
+	require.Equal(t, []*bar{want2}, items)
+	_, ok = index.Get("ipsum")
+	require.False(t, ok)
+
+	index.Delete(want2)
+	index.Delete(want3)
+	require.Nil(t, index.head.next[0])
+	require.Empty(t, index.itemKeys)
+}
+
+func TestOrderedIndex_AddAll(t *testing.T) {
+	bulk := NewOrderedIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+	single := NewOrderedIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+	existing := &bar{name: "existing", tags: []string{"key050", "key125"}}
+	bulk.Add(existing)
+	single.Add(existing)
+
+	var items []*bar
+	for i := 0; i < 200; i++ {
+		items = append(items, &bar{name: fmt.Sprint(i), tags: []string{fmt.Sprintf("key%03d", (i*37)%150), "key050"}})
+	}
+	bulk.AddAll(items)
+	for _, item := range items {
+		single.Add(item)
+	}
+
+	// Building in bulk gives the same order as adding one item at a time
+	require.Equal(t, single.Range("", "", RangeOptions{}), bulk.Range("", "", RangeOptions{}))
+	found, ok := bulk.Get("key050")
+	require.True(t, ok)
+	require.Len(t, found, 201)
+	require.Equal(t, existing, found[0])
+
+	var keys []string
+	bulk.Ascend(func(key string, item *bar) bool {
+		keys = append(keys, key)
+		return true
+	})
+	require.True(t, sort.StringsAreSorted(keys))
+
+	for _, item := range items {
+		bulk.Delete(item)
+	}
+	bulk.Delete(existing)
+	require.Nil(t, bulk.head.next[0])
+}
+
+func TestOrderedIndex_Update(t *testing.T) {
+	index := NewOrderedIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+
+	old := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	index.Add(old)
+
+	new := &bar{name: "one", tags: []string{"lorem", "dolor"}}
+	index.Update(old, new)
+
+	items, ok := index.Get("lorem")
+	require.True(t, ok)
+	require.Equal(t, []*bar{new}, items)
+	items, ok = index.Get("dolor")
+	require.True(t, ok)
+	require.Equal(t, []*bar{new}, items)
+	_, ok = index.Get("ipsum")
+	require.False(t, ok)
+}
+
+func TestOrderedIndex_Range(t *testing.T) {
+	index := NewOrderedIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+
+	a := &bar{name: "a", tags: []string{"2000"}}
+	b := &bar{name: "b", tags: []string{"2500", "2999"}}
+	c := &bar{name: "c", tags: []string{"2999"}}
+	d := &bar{name: "d", tags: []string{"3000"}}
+	e := &bar{name: "e", tags: []string{"1999"}}
+	for _, item := range []*bar{a, b, c, d, e} {
+		index.Add(item)
+	}
+
+	tests := []struct {
+		name string
+		lo   string
+		hi   string
+		opts RangeOptions
+		want []*bar
+	}{
+		{name: "inclusive bounds", lo: "2000", hi: "2999", want: []*bar{a, b, c}},
+		{name: "bounds between keys", lo: "2001", hi: "2998", want: []*bar{b}},
+		{name: "unbounded", want: []*bar{e, a, b, c, d}},
+		{name: "unbounded lo", hi: "2000", want: []*bar{e, a}},
+		{name: "unbounded hi", lo: "2999", want: []*bar{b, c, d}},
+		{name: "limit", lo: "2000", opts: RangeOptions{Limit: 2}, want: []*bar{a, b}},
+		{name: "limit with duplicate keys", lo: "2500", opts: RangeOptions{Limit: 2}, want: []*bar{b, c}},
+		{name: "descending", lo: "2000", hi: "2999", opts: RangeOptions{Descending: true}, want: []*bar{c, b, a}},
+		{name: "descending limit", opts: RangeOptions{Descending: true, Limit: 2}, want: []*bar{d, c}},
+		{name: "empty range", lo: "4000", hi: "5000", want: []*bar{}},
+		{name: "lo after hi", lo: "2999", hi: "2000", want: []*bar{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, index.Range(tt.lo, tt.hi, tt.opts))
+		})
+	}
+}
+
+func TestOrderedIndex_Ascend(t *testing.T) {
+	index := NewOrderedIndex[*bar]("name", func(bar *bar) []string { return []string{bar.name} })
+
+	names := []string{"m", "c", "x", "a", "p", "f"}
+	for _, name := range names {
+		index.Add(&bar{name: name})
+	}
+
+	var got []string
+	index.Ascend(func(key string, item *bar) bool {
+		got = append(got, item.name)
+		return true
+	})
+	require.Equal(t, []string{"a", "c", "f", "m", "p", "x"}, got)
+
+	got = nil
+	index.Ascend(func(key string, item *bar) bool {
+		got = append(got, key)
+		return len(got) < 3
+	})
+	require.Equal(t, []string{"a", "c", "f"}, got)
+}
+
+func TestOrderedIndex_manyKeys(t *testing.T) {
+	index := NewOrderedIndex[*bar]("name", func(bar *bar) []string { return []string{bar.name} })
+
+	// Insert in a scrambled order so that nodes are linked at several levels
+	items := make([]*bar, 1000)
+	for i := range items {
+		items[i] = &bar{name: fmt.Sprintf("%04d", (i*7919)%1000)}
+		index.Add(items[i])
+	}
+	for i := 0; i < len(items); i += 2 {
+		index.Delete(items[i])
+	}
+
+	var keys []string
+	index.Ascend(func(key string, item *bar) bool {
+		keys = append(keys, key)
+		return true
+	})
+	require.Len(t, keys, 500)
+	require.True(t, sort.StringsAreSorted(keys))
+
+	got := index.Range("0100", "0199", RangeOptions{})
+	for _, item := range got {
+		require.True(t, item.name >= "0100" && item.name <= "0199")
+	}
+	require.Len(t, got, 50)
+}
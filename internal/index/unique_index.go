@@ -0,0 +1,108 @@
+package index
+
+import "fmt"
+
+// ConflictError is returned when an item has a key that already belongs to a
+// different item in a UniqueIndex.
+type ConflictError[T comparable] struct {
+	// Index is the name of the unique index.
+	Index string
+	Key   string
+	// Existing is the item that the key already belongs to.
+	Existing T
+}
+
+func (e *ConflictError[T]) Error() string {
+	return fmt.Sprintf("unique constraint %s violated: %s already exists", e.Index, e.Key)
+}
+
+// Checker is implemented by indexes that constrain which items can be added.
+type Checker[T comparable] interface {
+	// Check returns an error if the old item can not be replaced by the new item.
+	// Pass the zero value of T as the old item to check a new item.
+	Check(old T, new T) error
+}
+
+// UniqueIndex is a map index that allows at most one item per key. Each item can
+// have multiple keys, all of which must be unique. Use Check to find conflicts
+// before adding or updating an item, as Add and Update replace the item that a
+// key belongs to.
+type UniqueIndex[T comparable] struct {
+	name  string
+	index map[string]T
+	// itemKeys holds the keys each item is indexed under.
+	itemKeys map[T][]string
+	// A function to specify the keys the item should be indexed under. Return
+	// an empty slice to skip the item.
+	keysFn func(T) []string
+}
+
+// NewUniqueIndex returns a new UniqueIndex.
+func NewUniqueIndex[T comparable](name string, keysFn func(T) []string) *UniqueIndex[T] {
+	return &UniqueIndex[T]{
+		name:     name,
+		index:    map[string]T{},
+		itemKeys: map[T][]string{},
+		keysFn:   keysFn,
+	}
+}
+
+// Name returns the name of the index.
+func (i *UniqueIndex[T]) Name() string {
+	return i.name
+}
+
+// Check returns a ConflictError if any key of the new item belongs to an item
+// other than the old item.
+func (i *UniqueIndex[T]) Check(old T, new T) error {
+	seen := map[string]bool{}
+	for _, key := range i.keysFn(new) {
+		if existing, ok := i.index[key]; ok && existing != old {
+			return &ConflictError[T]{Index: i.name, Key: key, Existing: existing}
+		} else if seen[key] {
+			return &ConflictError[T]{Index: i.name, Key: key, Existing: new}
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// keys returns the keys the item would be indexed under.
+func (i *UniqueIndex[T]) keys(item T) []string {
+	return i.keysFn(item)
+}
+
+// Add adds a new item to the index under each of its keys.
+func (i *UniqueIndex[T]) Add(item T) {
+	keys := i.keysFn(item)
+	for _, key := range keys {
+		i.index[key] = item
+	}
+	if len(keys) > 0 {
+		i.itemKeys[item] = keys
+	}
+}
+
+// Update replaces the old item with the new item.
+func (i *UniqueIndex[T]) Update(old T, new T) {
+	i.Delete(old)
+	i.Add(new)
+}
+
+// Get returns the item for the specified key.
+func (i *UniqueIndex[T]) Get(key string) ([]T, bool) {
+	if item, ok := i.index[key]; ok {
+		return []T{item}, true
+	}
+	return nil, false
+}
+
+// Delete removes the specified item from each of the keys it is indexed under.
+func (i *UniqueIndex[T]) Delete(item T) {
+	for _, key := range i.itemKeys[item] {
+		if existing, ok := i.index[key]; ok && existing == item {
+			delete(i.index, key)
+		}
+	}
+	delete(i.itemKeys, item)
+}
@@ -0,0 +1,73 @@
+package index
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniqueIndex(t *testing.T) {
+	index := NewUniqueIndex[*bar]("tags", func(bar *bar) []string { return bar.tags })
+
+	want1 := &bar{name: "one", tags: []string{"lorem", "ipsum"}}
+	want2 := &bar{name: "two", tags: []string{"dolor"}}
+	require.NoError(t, index.Check(nil, want1))
+	index.Add(want1)
+	require.NoError(t, index.Check(nil, want2))
+	index.Add(want2)
+
+	items, ok := index.Get("ipsum")
+	require.True(t, ok)
+	require.Equal(t, []*bar{want1}, items)
+
+	// Conflicting keys are rejected
+	conflicting := &bar{name: "three", tags: []string{"sit", "ipsum"}}
+	err := index.Check(nil, conflicting)
+	var conflictErr *ConflictError[*bar]
+	require.True(t, errors.As(err, &conflictErr))
+	require.Equal(t, "tags", conflictErr.Index)
+	require.Equal(t, "ipsum", conflictErr.Key)
+	require.Equal(t, want1, conflictErr.Existing)
+	require.EqualError(t, err, "unique constraint tags violated: ipsum already exists")
+
+	// Duplicate keys within an item are rejected
+	require.Error(t, index.Check(nil, &bar{name: "four", tags: []string{"sit", "sit"}}))
+
+	// An item can be replaced by an item with the same keys
+	updated := &bar{name: "one", tags: []string{"ipsum", "sit"}}
+	require.NoError(t, index.Check(want1, updated))
+	index.Update(want1, updated)
+	_, ok = index.Get("lorem")
+	require.False(t, ok)
+	items, ok = index.Get("ipsum")
+	require.True(t, ok)
+	require.Equal(t, []*bar{updated}, items)
+
+	index.Delete(updated)
+	index.Delete(want2)
+	require.Empty(t, index.index)
+	require.Empty(t, index.itemKeys)
+}
+
+func TestIndexes_Check(t *testing.T) {
+	indexes := NewIndexes[*bar](
+		NewMultiMapIndex[*bar]("tags", func(bar *bar) []string { return bar.tags }),
+		NewUniqueIndex[*bar]("name", func(bar *bar) []string { return []string{bar.name} }),
+	)
+
+	existing := &bar{name: "one", tags: []string{"lorem"}}
+	indexes.Add(existing)
+
+	require.NoError(t, indexes.Check(nil, &bar{name: "two", tags: []string{"lorem"}}))
+	require.EqualError(t, indexes.Check(nil, &bar{name: "one"}), "unique constraint name violated: one already exists")
+	require.NoError(t, indexes.Check(existing, &bar{name: "one"}))
+
+	// Registering a unique index fails if existing items conflict
+	other := &bar{name: "two", tags: []string{"lorem"}}
+	indexes.Add(other)
+	uniqueTags := NewUniqueIndex[*bar]("unique_tags", func(bar *bar) []string { return bar.tags })
+	err := indexes.Register(uniqueTags, []*bar{existing, other})
+	require.EqualError(t, err, "unique constraint unique_tags violated: lorem already exists")
+	require.False(t, indexes.Has("unique_tags"))
+}
@@ -60,6 +60,36 @@ func (t *NumberTrie[T]) Get(number string) (T, bool) {
 	return none, false
 }
 
+// LongestPrefix returns the value associated with the longest number in the trie
+// that is a prefix of the specified number, along with that prefix. The number
+// itself is the longest possible prefix.
+func (t *NumberTrie[T]) LongestPrefix(number string) (string, T, bool) {
+	var value *T
+	length := 0
+	current := t.root
+
+	for i := 0; i < len(number); i++ {
+		if number[i] < '0' || number[i] > '9' {
+			break
+		}
+		current = current.children[number[i]-'0']
+		if current == nil {
+			break
+		}
+		if current.value != nil {
+			value = current.value
+			length = i + 1
+		}
+	}
+
+	if value == nil {
+		var none T
+		return "", none, false
+	}
+
+	return number[:length], *value, true
+}
+
 // FindByPrefix returns all values that are associated with numbers beginning
 // with the specified number prefix.
 func (t *NumberTrie[T]) FindByPrefix(numberPrefix string) ([]T, bool) {
@@ -96,6 +126,91 @@ func (t *NumberTrie[T]) FindByPrefix(numberPrefix string) ([]T, bool) {
 	return values, true
 }
 
+// FindByPattern returns all values that are associated with numbers matching
+// the specified pattern, which holds a digit or a '?' wildcard matching any
+// single digit at each position. Only numbers with the same length as the
+// pattern are matched, and only the branches that can match are searched.
+func (t *NumberTrie[T]) FindByPattern(pattern string) ([]T, bool) {
+	var values []T
+
+	nodes := []*numberTrieNode[T]{t.root}
+	for i := 0; i < len(pattern) && len(nodes) > 0; i++ {
+		var next []*numberTrieNode[T]
+		for _, node := range nodes {
+			if pattern[i] == '?' {
+				for _, childNode := range node.children {
+					if childNode != nil {
+						next = append(next, childNode)
+					}
+				}
+			} else if pattern[i] >= '0' && pattern[i] <= '9' {
+				if childNode := node.children[pattern[i]-'0']; childNode != nil {
+					next = append(next, childNode)
+				}
+			}
+		}
+		nodes = next
+	}
+
+	for _, node := range nodes {
+		if node.value != nil {
+			values = append(values, *node.value)
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	return values, true
+}
+
+// FindByRange returns the values associated with numbers between lo and hi
+// inclusive in ascending order. The bounds must have the same length, and only
+// numbers of that length are matched. Only the subtrees that can hold numbers
+// in the range are searched.
+func (t *NumberTrie[T]) FindByRange(lo string, hi string) ([]T, bool) {
+	var values []T
+
+	if !validRange(lo, hi) {
+		return nil, false
+	}
+
+	walkRange(t.root, lo, hi, false, func(_ string, node *numberTrieNode[T]) bool {
+		if node.value != nil {
+			values = append(values, *node.value)
+		}
+		return true
+	})
+
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	return values, true
+}
+
+// FreeNumbers returns the numbers between lo and hi inclusive that are not in
+// the trie in ascending order, which is useful for allocating new numbers from a
+// block. The bounds must have the same length. At most limit numbers are
+// returned, and none if limit is not positive.
+func (t *NumberTrie[T]) FreeNumbers(lo string, hi string, limit int) []string {
+	free := []string{}
+
+	if limit <= 0 || !validRange(lo, hi) {
+		return free
+	}
+
+	walkRange(t.root, lo, hi, true, func(number string, node *numberTrieNode[T]) bool {
+		if node == nil || node.value == nil {
+			free = append(free, number)
+		}
+		return len(free) < limit
+	})
+
+	return free
+}
+
 // Delete removes the specified number from the trie.
 func (t *NumberTrie[T]) Delete(number string) {
 	remove(number, t.root, 0)
@@ -120,6 +235,62 @@ func find[T any](number string, root *numberTrieNode[T]) (*numberTrieNode[T], bo
 	return nil, false
 }
 
+// walkRange calls fn in ascending order for each number between lo and hi
+// inclusive, which must have the same length, until fn returns false. Subtrees
+// that do not exist are skipped, unless missing is true in which case fn is
+// called with a nil node for each number they would hold.
+func walkRange[T any](root *numberTrieNode[T], lo string, hi string, missing bool, fn func(number string, node *numberTrieNode[T]) bool) {
+	digits := make([]byte, len(lo))
+
+	var walk func(node *numberTrieNode[T], depth int, lowerBound bool, upperBound bool) bool
+	walk = func(node *numberTrieNode[T], depth int, lowerBound bool, upperBound bool) bool {
+		if node == nil && !missing {
+			return true
+		}
+		if depth == len(lo) {
+			return fn(string(digits), node)
+		}
+
+		// Digits are only limited by a bound while the number so far is equal
+		// to the prefix of that bound
+		first, last := byte('0'), byte('9')
+		if lowerBound {
+			first = lo[depth]
+		}
+		if upperBound {
+			last = hi[depth]
+		}
+
+		for digit := first; digit <= last; digit++ {
+			var child *numberTrieNode[T]
+			if node != nil {
+				child = node.children[digit-'0']
+			}
+			digits[depth] = digit
+			if !walk(child, depth+1, lowerBound && digit == first, upperBound && digit == last) {
+				return false
+			}
+		}
+		return true
+	}
+
+	walk(root, 0, true, true)
+}
+
+// validRange reports whether lo and hi are digits of the same length, and lo is
+// not after hi.
+func validRange(lo string, hi string) bool {
+	if len(lo) != len(hi) || lo > hi {
+		return false
+	}
+	for i := 0; i < len(lo); i++ {
+		if lo[i] < '0' || lo[i] > '9' || hi[i] < '0' || hi[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func remove[T any](number string, node *numberTrieNode[T], depth int) *numberTrieNode[T] {
 	if node == nil {
 		return nil
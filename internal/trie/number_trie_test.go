@@ -132,3 +132,180 @@ func TestNumberTrie_Delete(t *testing.T) {
 	node = node.children[2]
 	require.Equal(t, wantItem, *node.value)
 }
+
+func TestNumberTrie_FindByRange(t *testing.T) {
+	trie := NewNumberTrie[string]()
+	for _, number := range []string{"0409999999", "0410000000", "0410012345", "0410049999", "0410050000", "041000"} {
+		require.NoError(t, trie.Insert(number, number))
+	}
+	trie.Delete("0410012345")
+
+	tests := []struct {
+		name      string
+		lo        string
+		hi        string
+		want      []string
+		wantFound bool
+	}{
+		{
+			name:      "numbers in block",
+			lo:        "0410000000",
+			hi:        "0410049999",
+			want:      []string{"0410000000", "0410049999"},
+			wantFound: true,
+		},
+		{
+			name:      "single number",
+			lo:        "0409999999",
+			hi:        "0409999999",
+			want:      []string{"0409999999"},
+			wantFound: true,
+		},
+		{
+			name:      "shorter numbers",
+			lo:        "041000",
+			hi:        "041999",
+			want:      []string{"041000"},
+			wantFound: true,
+		},
+		{
+			name:      "empty block",
+			lo:        "0420000000",
+			hi:        "0429999999",
+			wantFound: false,
+		},
+		{
+			name:      "bounds with different lengths",
+			lo:        "041",
+			hi:        "0410049999",
+			wantFound: false,
+		},
+		{
+			name:      "lo after hi",
+			lo:        "0410049999",
+			hi:        "0410000000",
+			wantFound: false,
+		},
+		{
+			name:      "invalid bounds",
+			lo:        "04100000ab",
+			hi:        "0410049999",
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := trie.FindByRange(tt.lo, tt.hi)
+			require.Equal(t, tt.wantFound, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNumberTrie_FreeNumbers(t *testing.T) {
+	trie := NewNumberTrie[foo]()
+	for _, number := range []string{"0410000000", "0410000001", "0410000003", "0410000010"} {
+		require.NoError(t, trie.Insert(number, foo{bar: number}))
+	}
+	trie.Delete("0410000001")
+
+	require.Equal(t, []string{"0410000001", "0410000002", "0410000004"}, trie.FreeNumbers("0410000000", "0410049999", 3))
+	require.Equal(t, []string{"0410000009", "0410000011"}, trie.FreeNumbers("0410000009", "0410000011", 10))
+	require.Equal(t, []string{"0420000000", "0420000001"}, trie.FreeNumbers("0420000000", "0420000001", 10))
+	require.Empty(t, trie.FreeNumbers("0410000010", "0410000010", 10))
+	require.Empty(t, trie.FreeNumbers("0410000011", "0410000010", 10))
+	require.Empty(t, trie.FreeNumbers("041", "0410000010", 10))
+	require.Empty(t, trie.FreeNumbers("0000000000", "9999999999", 0))
+}
+
+func TestNumberTrie_FindByPattern(t *testing.T) {
+	trie := NewNumberTrie[string]()
+	for _, number := range []string{"0410123456", "0420129999", "0410223456", "041012345", "0510123456"} {
+		require.NoError(t, trie.Insert(number, number))
+	}
+
+	tests := []struct {
+		name      string
+		pattern   string
+		want      []string
+		wantFound bool
+	}{
+		{
+			name:      "exact number",
+			pattern:   "0410123456",
+			want:      []string{"0410123456"},
+			wantFound: true,
+		},
+		{
+			name:      "wildcards",
+			pattern:   "04?012????",
+			want:      []string{"0410123456", "0420129999"},
+			wantFound: true,
+		},
+		{
+			name:      "leading wildcard",
+			pattern:   "?410123456",
+			want:      []string{"0410123456"},
+			wantFound: true,
+		},
+		{
+			name:      "only matches numbers with the pattern length",
+			pattern:   "?????????",
+			want:      []string{"041012345"},
+			wantFound: true,
+		},
+		{
+			name:      "no match",
+			pattern:   "04?0000???",
+			wantFound: false,
+		},
+		{
+			name:      "invalid characters",
+			pattern:   "04a0123456",
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := trie.FindByPattern(tt.pattern)
+			require.Equal(t, tt.wantFound, ok)
+			require.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestNumberTrie_LongestPrefix(t *testing.T) {
+	trie := NewNumberTrie[string]()
+	require.NoError(t, trie.Insert("61", "AU"))
+	require.NoError(t, trie.Insert("614", "AU mobile"))
+	require.NoError(t, trie.Insert("61410", "AU carrier"))
+	require.NoError(t, trie.Insert("1", "NANP"))
+
+	tests := []struct {
+		name       string
+		number     string
+		wantPrefix string
+		wantValue  string
+		wantFound  bool
+	}{
+		{name: "deepest prefix", number: "61410000000", wantPrefix: "61410", wantValue: "AU carrier", wantFound: true},
+		{name: "intermediate prefix", number: "61420000000", wantPrefix: "614", wantValue: "AU mobile", wantFound: true},
+		{name: "shortest prefix", number: "61299999999", wantPrefix: "61", wantValue: "AU", wantFound: true},
+		{name: "number is a key", number: "614", wantPrefix: "614", wantValue: "AU mobile", wantFound: true},
+		{name: "single digit prefix", number: "15551234567", wantPrefix: "1", wantValue: "NANP", wantFound: true},
+		{name: "no prefix", number: "44207946000", wantFound: false},
+		{name: "shorter than prefixes", number: "6", wantFound: false},
+		{name: "invalid characters", number: "6a14", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, value, ok := trie.LongestPrefix(tt.number)
+			require.Equal(t, tt.wantFound, ok)
+			require.Equal(t, tt.wantPrefix, prefix)
+			require.Equal(t, tt.wantValue, value)
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package phonebook
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// AuditAction is the kind of change recorded by an audit record.
+type AuditAction string
+
+const (
+	AuditAdded   AuditAction = "added"
+	AuditUpdated AuditAction = "updated"
+	AuditDeleted AuditAction = "deleted"
+	// AuditPurged records that a contact was permanently deleted from the trash.
+	AuditPurged AuditAction = "purged"
+)
+
+// AuditRecord records who changed a contact and which of its fields changed.
+// Records do not hold the values of fields, so that no data remains in the
+// audit log once a contact is deleted. Each record holds the hash of the
+// previous record, which chains the records together so that tampering with the
+// log can be detected with VerifyAuditLog.
+type AuditRecord struct {
+	// Sequence is the position of the record in the audit log, starting at one.
+	Sequence  uint64      `json:"sequence"`
+	Time      time.Time   `json:"time"`
+	Actor     string      `json:"actor"`
+	Action    AuditAction `json:"action"`
+	ContactID string      `json:"contact_id"`
+	// Fields are the names of the fields that changed (see FieldChange).
+	Fields []string `json:"fields,omitempty"`
+	// PrevHash is the hash of the previous record, which is empty for the first
+	// record.
+	PrevHash string `json:"prev_hash"`
+	// Hash is the SHA-256 hash of the record, excluding the hash itself.
+	Hash string `json:"hash"`
+}
+
+// AuditSink stores audit records. Records must be stored in the order they are
+// written, and never modified. A sink may also implement
+// WriteAll([]AuditRecord) error to store the records of a transaction together,
+// so that either all or none of them are stored.
+type AuditSink interface {
+	Write(record AuditRecord) error
+}
+
+// JSONAuditSink is an AuditSink that writes each record as a line of JSON.
+type JSONAuditSink struct {
+	w    io.Writer
+	last *AuditRecord
+}
+
+// NewJSONAuditSink returns a new JSONAuditSink writing to w.
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w}
+}
+
+// Write writes the record as a line of JSON.
+func (s *JSONAuditSink) Write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.last = &record
+	return nil
+}
+
+// WriteAll writes the records as lines of JSON with a single write.
+func (s *JSONAuditSink) WriteAll(records []AuditRecord) error {
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if _, err := s.w.Write(lines); err != nil {
+		return err
+	}
+	if len(records) > 0 {
+		s.last = &records[len(records)-1]
+	}
+	return nil
+}
+
+// Last returns the last record written to the sink.
+func (s *JSONAuditSink) Last() (AuditRecord, bool) {
+	if s.last == nil {
+		return AuditRecord{}, false
+	}
+	return *s.last, true
+}
+
+// FileAuditSink is a JSONAuditSink that appends records to a file.
+type FileAuditSink struct {
+	*JSONAuditSink
+	file *os.File
+}
+
+// OpenAuditFile opens a file to append audit records to as lines of JSON,
+// creating it if it does not exist. The chain of an existing file is verified,
+// and continued by the records written to the sink.
+func OpenAuditFile(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := &FileAuditSink{JSONAuditSink: NewJSONAuditSink(file), file: file}
+	last, err := verifyAuditLog(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if last.Sequence > 0 {
+		sink.last = &last
+	}
+	return sink, nil
+}
+
+// Close closes the file.
+func (s *FileAuditSink) Close() error {
+	return s.file.Close()
+}
+
+// ContextWithActor returns a copy of the context holding the actor, which is
+// recorded in the history and audit log of the changes made with the context.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor held by the context.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
+
+type actorKey struct{}
+
+// VerifyAuditLog checks that the records read from r, as written by a
+// JSONAuditSink, form an unbroken hash chain. It returns the number of records
+// verified, or an error describing the first record that is invalid.
+func VerifyAuditLog(r io.Reader) (int, error) {
+	last, err := verifyAuditLog(r)
+	return int(last.Sequence), err
+}
+
+// verifyAuditLog verifies the records read from r, and returns the last record.
+func verifyAuditLog(r io.Reader) (AuditRecord, error) {
+	var last AuditRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return last, fmt.Errorf("audit record on line %d is invalid: %w", line, err)
+		}
+		if record.Sequence != last.Sequence+1 {
+			return last, fmt.Errorf("audit record on line %d has sequence %d, expected %d", line, record.Sequence, last.Sequence+1)
+		}
+		if record.PrevHash != last.Hash {
+			return last, fmt.Errorf("audit record %d does not follow the previous record", record.Sequence)
+		}
+		if hash, err := auditHash(record); err != nil || hash != record.Hash {
+			return last, fmt.Errorf("audit record %d has been modified", record.Sequence)
+		}
+		last = record
+	}
+	return last, scanner.Err()
+}
+
+// audit chains the records to the last record written and writes them to the
+// audit sink, if configured. The records are written together if the sink
+// implements WriteAll.
+func (p *PhoneBook) audit(records ...AuditRecord) error {
+	if p.auditSink == nil || len(records) == 0 {
+		return nil
+	}
+
+	last, ok := p.lastAuditRecord()
+	for i := range records {
+		if ok {
+			records[i].Sequence = last.Sequence + 1
+			records[i].PrevHash = last.Hash
+		} else {
+			records[i].Sequence = 1
+		}
+		hash, err := auditHash(records[i])
+		if err != nil {
+			return err
+		}
+		records[i].Hash = hash
+		last, ok = records[i], true
+	}
+
+	if sink, ok := p.auditSink.(interface{ WriteAll([]AuditRecord) error }); ok {
+		if err := sink.WriteAll(records); err != nil {
+			return fmt.Errorf("failed to write audit record: %w", err)
+		}
+		p.auditLast = &last
+		return nil
+	}
+	for i := range records {
+		if err := p.auditSink.Write(records[i]); err != nil {
+			return fmt.Errorf("failed to write audit record: %w", err)
+		}
+		p.auditLast = &records[i]
+	}
+	return nil
+}
+
+// newAuditRecord returns an unchained record of the change to the contact. Pass
+// a nil before contact for an added contact, or a nil after contact for a
+// deleted contact.
+func newAuditRecord(ctx context.Context, action AuditAction, before *Contact, after *Contact) AuditRecord {
+	record := AuditRecord{Time: time.Now().UTC(), Action: action}
+	record.Actor, _ = ActorFromContext(ctx)
+	if after != nil {
+		record.ContactID = after.ID
+	} else {
+		record.ContactID = before.ID
+	}
+	if action != AuditPurged {
+		for _, change := range diff(before, after) {
+			record.Fields = append(record.Fields, change.Field)
+		}
+	}
+	return record
+}
+
+// auditRecord returns an unchained record of the change.
+func (c change) auditRecord(ctx context.Context) AuditRecord {
+	switch {
+	case c.before == nil:
+		return newAuditRecord(ctx, AuditAdded, nil, c.after)
+	case c.after == nil:
+		return newAuditRecord(ctx, AuditDeleted, c.before, nil)
+	default:
+		return newAuditRecord(ctx, AuditUpdated, c.before, c.after)
+	}
+}
+
+// lastAuditRecord returns the last record written by the phone book, or the last
+// record of the sink if it reports one.
+func (p *PhoneBook) lastAuditRecord() (AuditRecord, bool) {
+	if p.auditLast != nil {
+		return *p.auditLast, true
+	}
+	if sink, ok := p.auditSink.(interface{ Last() (AuditRecord, bool) }); ok {
+		return sink.Last()
+	}
+	return AuditRecord{}, false
+}
+
+func auditHash(record AuditRecord) (string, error) {
+	record.Hash = ""
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
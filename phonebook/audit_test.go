@@ -0,0 +1,179 @@
+package phonebook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_audit(t *testing.T) {
+	var log bytes.Buffer
+	phoneBook := New(WithAuditSink(NewJSONAuditSink(&log)), WithTrash(0))
+	ctx := ContextWithActor(context.Background(), "alice")
+
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	id, err := phoneBook.Add(ctx, contact)
+	require.NoError(t, err)
+	contact.ID = id
+	updated := contact
+	updated.LastName = "Updated"
+	require.NoError(t, phoneBook.UpdateByID(ContextWithActor(context.Background(), "bob"), id, updated))
+	require.NoError(t, phoneBook.Delete(ctx, "0123456789"))
+	require.NoError(t, phoneBook.Purge(context.Background(), "0123456789"))
+
+	records := auditRecords(t, log.String())
+	require.Len(t, records, 4)
+	want := []struct {
+		actor  string
+		action AuditAction
+		fields []string
+	}{
+		{actor: "alice", action: AuditAdded, fields: []string{"numbers", "first_name", "last_name"}},
+		{actor: "bob", action: AuditUpdated, fields: []string{"last_name"}},
+		{actor: "alice", action: AuditDeleted, fields: []string{"numbers", "first_name", "last_name"}},
+		{actor: "", action: AuditPurged},
+	}
+	for i, record := range records {
+		require.Equal(t, uint64(i+1), record.Sequence)
+		require.Equal(t, want[i].actor, record.Actor)
+		require.Equal(t, want[i].action, record.Action)
+		require.Equal(t, want[i].fields, record.Fields)
+		require.Equal(t, id, record.ContactID)
+		if i > 0 {
+			require.Equal(t, records[i-1].Hash, record.PrevHash)
+		}
+	}
+
+	// The audit log holds no contact data
+	require.NotContains(t, log.String(), "0123456789")
+	require.NotContains(t, log.String(), "Updated")
+
+	count, err := VerifyAuditLog(strings.NewReader(log.String()))
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+}
+
+func TestPhoneBook_audit_actorHistory(t *testing.T) {
+	phoneBook := New()
+	ctx := ContextWithActor(context.Background(), "alice")
+	_, err := phoneBook.Add(ctx, Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"})
+	require.NoError(t, err)
+
+	history, err := phoneBook.History("0123456789")
+	require.NoError(t, err)
+	require.Equal(t, "alice", history[0].Actor)
+
+	actor, ok := ActorFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, "alice", actor)
+	_, ok = ActorFromContext(context.Background())
+	require.False(t, ok)
+}
+
+func TestPhoneBook_audit_sinkError(t *testing.T) {
+	phoneBook := New(WithAuditSink(failingSink{}))
+
+	// Changes that can not be audited are not made
+	_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"})
+	require.EqualError(t, err, "failed to write audit record: sink unavailable")
+	_, ok := phoneBook.Get("0123456789")
+	require.False(t, ok)
+	require.Empty(t, phoneBook.history)
+	require.Equal(t, uint64(0), phoneBook.Sequence())
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	var log bytes.Buffer
+	phoneBook := New(WithAuditSink(NewJSONAuditSink(&log)))
+	for _, number := range []string{"0123456789", "9876543210", "5432167890"} {
+		_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile(number), FirstName: "Foo", LastName: "Bar"})
+		require.NoError(t, err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(log.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+
+	modified := auditRecords(t, lines[1])[0]
+	modified.Actor = "mallory"
+	modifiedLine, err := json.Marshal(modified)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		log       string
+		wantCount int
+		wantErr   string
+	}{
+		{name: "valid", log: log.String(), wantCount: 3},
+		{name: "empty", log: "", wantCount: 0},
+		{name: "modified record", log: lines[0] + string(modifiedLine) + "\n" + lines[2], wantCount: 1, wantErr: "audit record 2 has been modified"},
+		{name: "removed record", log: lines[0] + lines[2], wantCount: 1, wantErr: "audit record on line 2 has sequence 3, expected 2"},
+		{name: "reordered records", log: lines[1] + lines[0], wantCount: 0, wantErr: "audit record on line 1 has sequence 2, expected 1"},
+		{name: "invalid record", log: lines[0] + "foo\n", wantCount: 1, wantErr: "audit record on line 2 is invalid: invalid character 'o' in literal false (expecting 'a')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := VerifyAuditLog(strings.NewReader(tt.log))
+			require.Equal(t, tt.wantCount, count)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOpenAuditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := OpenAuditFile(path)
+	require.NoError(t, err)
+	phoneBook := New(WithAuditSink(sink))
+	_, err = phoneBook.Add(context.Background(), Contact{Numbers: mobile("0123456789"), FirstName: "Foo", LastName: "Bar"})
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	// Reopening the file continues its chain
+	sink, err = OpenAuditFile(path)
+	require.NoError(t, err)
+	phoneBook = New(WithAuditSink(sink))
+	_, err = phoneBook.Add(context.Background(), Contact{Numbers: mobile("9876543210"), FirstName: "Foo", LastName: "Bar"})
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	count, err := VerifyAuditLog(file)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	// Files with a broken chain are not appended to
+	require.NoError(t, os.WriteFile(path, []byte("foo\n"), 0o600))
+	_, err = OpenAuditFile(path)
+	require.Error(t, err)
+}
+
+type failingSink struct{}
+
+func (failingSink) Write(AuditRecord) error {
+	return errors.New("sink unavailable")
+}
+
+func auditRecords(t *testing.T, log string) []AuditRecord {
+	var records []AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(log), "\n") {
+		var record AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
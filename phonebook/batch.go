@@ -0,0 +1,270 @@
+package phonebook
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BatchMode controls how a batch handles an item that fails.
+type BatchMode int
+
+const (
+	// BestEffort applies every item in the batch that can be applied, and
+	// reports the items that fail.
+	BestEffort BatchMode = iota
+	// StopOnError stops the batch at the first item that fails. The items before
+	// it are applied, and the items after it are skipped.
+	StopOnError
+)
+
+// BatchStatus is the outcome of an item in a batch.
+type BatchStatus string
+
+const (
+	BatchOK BatchStatus = "ok"
+	// BatchDuplicate reports an item with a number or unique key that already
+	// belongs to another contact, including a contact earlier in the batch.
+	BatchDuplicate BatchStatus = "duplicate"
+	// BatchInvalid reports an item that is not a valid contact or number.
+	BatchInvalid BatchStatus = "invalid"
+	// BatchNotFound reports a number to delete that does not belong to a
+	// contact, which is not an error, or the ID of a contact to upsert that
+	// does not exist, which is.
+	BatchNotFound BatchStatus = "not_found"
+	// BatchFailed reports an item that is valid but could not be applied, such
+	// as when its change could not be audited.
+	BatchFailed BatchStatus = "failed"
+	// BatchSkipped reports an item that was not attempted because an earlier
+	// item failed in StopOnError mode.
+	BatchSkipped BatchStatus = "skipped"
+)
+
+// BatchResult is the outcome of an item in a batch. Results are returned in the
+// same order as the items.
+type BatchResult struct {
+	// ID is the ID of the contact that was added, replaced or deleted.
+	ID     string
+	Status BatchStatus
+	// Replaced reports whether an upserted contact replaced an existing contact.
+	Replaced bool
+	// Err is the error of an item that failed, which is nil if the item did not
+	// fail.
+	Err error
+}
+
+// AddMany adds the contacts to the phone book and returns the result of each,
+// which is faster than calling Add for each contact. The contacts are checked
+// against the phone book and each other, and are then audited and added to every
+// index together. If the changes can not be audited, none of the contacts are
+// added and each reports BatchFailed.
+func (p *PhoneBook) AddMany(ctx context.Context, contacts []Contact, mode BatchMode) []BatchResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expireTrash()
+	results := make([]BatchResult, len(contacts))
+	batch := p.indexes.NewBatch()
+	// numbers holds the numbers of the contacts in the batch
+	numbers := map[string]bool{}
+	var added []*Contact
+	stopped := false
+	for i, contact := range contacts {
+		if stopped {
+			results[i] = BatchResult{Status: BatchSkipped}
+			continue
+		}
+
+		staged, status, err := p.stage(nil, contact)
+		if err == nil {
+			if number, ok := batchNumberConflict(staged, numbers); ok {
+				status, err = BatchDuplicate, fmt.Errorf("number already exists: %s", number)
+			} else if err = constraintError(batch.Check(&staged)); err != nil {
+				status = BatchDuplicate
+			}
+		}
+		if err != nil {
+			results[i] = BatchResult{Status: status, Err: err}
+			stopped = mode == StopOnError
+			continue
+		}
+
+		staged.ID = newID(time.Now())
+		for _, number := range staged.NumberStrings() {
+			numbers[numberKey(number)] = true
+		}
+		batch.Add(&staged)
+		added = append(added, &staged)
+		results[i] = BatchResult{ID: staged.ID, Status: BatchOK}
+	}
+
+	records := make([]AuditRecord, len(added))
+	for i, contact := range added {
+		records[i] = change{after: contact}.auditRecord(ctx)
+	}
+	if err := p.audit(records...); err != nil {
+		failApplied(results, err)
+		return results
+	}
+
+	for _, contact := range added {
+		p.contacts[contact.ID] = contact
+		p.reindexTries(nil, contact)
+	}
+	batch.Commit()
+	for _, contact := range added {
+		p.publish(ctx, change{after: contact})
+	}
+	return results
+}
+
+// UpsertMany adds each contact, or replaces the existing contact that owns any
+// of its numbers, and returns the result of each. A contact with an ID replaces
+// the contact with that ID instead, and reports BatchNotFound if there is no
+// such contact. Each contact is applied in order as if by
+// Upsert within a transaction, so a later contact can replace an earlier one,
+// and the changes are then audited together. If the changes can not be
+// audited, none of the contacts are applied and each that was applied reports
+// BatchFailed.
+func (p *PhoneBook) UpsertMany(ctx context.Context, contacts []Contact, mode BatchMode) []BatchResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	results := make([]BatchResult, len(contacts))
+	tx := &Tx{p: p, ctx: ctx}
+	err := p.atomically(tx, func() error {
+		stopped := false
+		for i, contact := range contacts {
+			if stopped {
+				results[i] = BatchResult{Status: BatchSkipped}
+				continue
+			}
+			results[i] = p.upsert(ctx, contact)
+			stopped = mode == StopOnError && results[i].Err != nil
+		}
+		return nil
+	})
+	if err != nil {
+		failApplied(results, err)
+	}
+	return results
+}
+
+// DeleteMany deletes the contacts that own the numbers, as if by Delete, and
+// returns the result of each. A number that does not belong to a contact reports
+// BatchNotFound, including a number of a contact deleted earlier in the batch.
+// The deletes are checked first, and are then audited and removed from every
+// index together. If the changes can not be audited, none of the contacts are
+// deleted and each reports BatchFailed.
+func (p *PhoneBook) DeleteMany(ctx context.Context, numbers []string, mode BatchMode) []BatchResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expireTrash()
+	results := make([]BatchResult, len(numbers))
+	// deleted holds the IDs of the contacts deleted by the batch
+	deleted := map[string]bool{}
+	var changes []change
+	stopped := false
+	for i, number := range numbers {
+		if stopped {
+			results[i] = BatchResult{Status: BatchSkipped}
+			continue
+		}
+
+		if _, err := p.policy.Normalize(number); err != nil {
+			results[i] = BatchResult{Status: BatchInvalid, Err: err}
+			stopped = mode == StopOnError
+			continue
+		}
+		existing, ok := p.contactByNumber(number)
+		if !ok || deleted[existing.ID] {
+			results[i] = BatchResult{Status: BatchNotFound}
+			continue
+		}
+
+		deleted[existing.ID] = true
+		changes = append(changes, change{before: existing})
+		results[i] = BatchResult{ID: existing.ID, Status: BatchOK}
+	}
+
+	records := make([]AuditRecord, len(changes))
+	for i, c := range changes {
+		records[i] = c.auditRecord(ctx)
+	}
+	if err := p.audit(records...); err != nil {
+		failApplied(results, err)
+		return results
+	}
+
+	for _, c := range changes {
+		p.store(c)
+	}
+	for _, c := range changes {
+		p.publish(ctx, c)
+	}
+	return results
+}
+
+// upsert adds the contact, or replaces the existing contact with its ID or any
+// of its numbers.
+func (p *PhoneBook) upsert(ctx context.Context, contact Contact) BatchResult {
+	existing, ok := p.upsertTarget(contact)
+	if !ok && contact.ID != "" {
+		return BatchResult{Status: BatchNotFound, Err: fmt.Errorf("contact not found for ID %s", contact.ID)}
+	} else if !ok {
+		staged, status, err := p.stage(nil, contact)
+		if err != nil {
+			return BatchResult{Status: status, Err: err}
+		}
+		staged.ID = newID(time.Now())
+		if err := p.insert(ctx, &staged); err != nil {
+			return BatchResult{Status: BatchFailed, Err: err}
+		}
+		return BatchResult{ID: staged.ID, Status: BatchOK}
+	}
+
+	staged, status, err := p.stage(existing, contact)
+	if err != nil {
+		return BatchResult{Status: status, Err: err}
+	}
+	if err := p.replace(ctx, existing, &staged); err != nil {
+		return BatchResult{Status: BatchFailed, Err: err}
+	}
+	return BatchResult{ID: staged.ID, Status: BatchOK, Replaced: true}
+}
+
+// upsertTarget returns the contact that an upserted contact replaces.
+func (p *PhoneBook) upsertTarget(contact Contact) (*Contact, bool) {
+	if contact.ID != "" {
+		existing, ok := p.contacts[contact.ID]
+		return existing, ok
+	}
+	for _, number := range contact.Numbers {
+		if existing, ok := p.contactByNumber(number.Number); ok {
+			return existing, true
+		}
+	}
+	return nil, false
+}
+
+// batchNumberConflict returns the first of the contact's numbers that belongs to
+// a contact earlier in a batch.
+func batchNumberConflict(contact Contact, numbers map[string]bool) (string, bool) {
+	for _, number := range contact.NumberStrings() {
+		if numbers[numberKey(number)] {
+			return number, true
+		}
+	}
+	return "", false
+}
+
+// failApplied reports each item of a batch that was applied as BatchFailed with
+// the error, once the batch's changes could not be audited.
+func failApplied(results []BatchResult, err error) {
+	for i, result := range results {
+		if result.Status == BatchOK {
+			results[i] = BatchResult{Status: BatchFailed, Err: err}
+		}
+	}
+}
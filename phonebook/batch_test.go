@@ -0,0 +1,225 @@
+package phonebook
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_AddMany(t *testing.T) {
+	var log bytes.Buffer
+	phoneBook := New(WithUniqueEmails(), WithAuditSink(NewJSONAuditSink(&log)))
+	existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Emails: []Email{{Type: Personal, Address: "one@example.com"}}}
+	add(t, phoneBook, &existing)
+	sub, err := phoneBook.Subscribe(Filter{})
+	require.NoError(t, err)
+
+	contacts := []Contact{
+		{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: newAddress("Foo City")},
+		{Numbers: mobile("0123456789"), FirstName: "Three", LastName: "Three"},
+		{Numbers: mobile("987 654 3210"), FirstName: "Four", LastName: "Four"},
+		{Numbers: mobile("123"), FirstName: "Five", LastName: "Five"},
+		{Numbers: mobile("5432167890"), FirstName: "Six", LastName: "Six", Emails: []Email{{Type: Personal, Address: "one@example.com"}}},
+		{Numbers: mobile("5432167890"), FirstName: "Seven", LastName: "Seven", Address: newAddress("Foo City"), Emails: []Email{{Type: Business, Address: "seven@example.com"}}},
+		{Numbers: mobile("1111111111"), FirstName: "Eight", LastName: "Eight", Emails: []Email{{Type: Business, Address: "SEVEN@example.com"}}},
+	}
+	results := phoneBook.AddMany(context.Background(), contacts, BestEffort)
+	require.Len(t, results, len(contacts))
+
+	tests := []struct {
+		status  BatchStatus
+		wantErr string
+	}{
+		{status: BatchOK},
+		{status: BatchDuplicate, wantErr: "number already exists: 0123456789"},
+		{status: BatchDuplicate, wantErr: "number already exists: 9876543210"},
+		{status: BatchInvalid, wantErr: "phone number must contain 10 digits"},
+		{status: BatchDuplicate, wantErr: "unique constraint email violated: one@example.com already exists"},
+		{status: BatchOK},
+		{status: BatchDuplicate, wantErr: "unique constraint email violated: seven@example.com already exists"},
+	}
+	for i, tt := range tests {
+		require.Equal(t, tt.status, results[i].Status, "item %d", i)
+		if tt.wantErr != "" {
+			require.EqualError(t, results[i].Err, tt.wantErr, "item %d", i)
+			require.Empty(t, results[i].ID)
+		} else {
+			require.NoError(t, results[i].Err)
+			require.NotEmpty(t, results[i].ID)
+		}
+	}
+	var constraintErr *ConstraintError
+	require.ErrorAs(t, results[6].Err, &constraintErr)
+	require.Equal(t, results[5].ID, constraintErr.ContactID)
+
+	// The added contacts are in every index, and are audited and published
+	two, ok := phoneBook.Get("9876543210")
+	require.True(t, ok)
+	require.Equal(t, results[0].ID, two.ID)
+	require.Len(t, phoneBook.FindByCity("Foo City"), 2)
+	require.Len(t, phoneBook.FindByEmail("seven@example.com"), 1)
+	require.Len(t, phoneBook.FindByKeypad("7383"), 1)
+	found, err := phoneBook.FindByRange("first_name", "S", "T", RangeOptions{})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Len(t, auditRecords(t, log.String()), 3)
+	require.Equal(t, results[0].ID, (<-sub.Events()).After.ID)
+	require.Equal(t, results[5].ID, (<-sub.Events()).After.ID)
+}
+
+func TestPhoneBook_AddMany_stopOnError(t *testing.T) {
+	phoneBook := New()
+	contacts := []Contact{
+		{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"},
+		{Numbers: mobile("123"), FirstName: "Two", LastName: "Two"},
+		{Numbers: mobile("9876543210"), FirstName: "Three", LastName: "Three"},
+	}
+	results := phoneBook.AddMany(context.Background(), contacts, StopOnError)
+	require.Equal(t, BatchOK, results[0].Status)
+	require.Equal(t, BatchInvalid, results[1].Status)
+	require.Equal(t, BatchResult{Status: BatchSkipped}, results[2])
+
+	_, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	_, ok = phoneBook.Get("9876543210")
+	require.False(t, ok)
+}
+
+func TestPhoneBook_AddMany_auditError(t *testing.T) {
+	phoneBook := New(WithAuditSink(failingSink{}))
+	results := phoneBook.AddMany(context.Background(), []Contact{
+		{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"},
+		{Numbers: mobile("123"), FirstName: "Two", LastName: "Two"},
+	}, BestEffort)
+
+	require.Equal(t, BatchFailed, results[0].Status)
+	require.EqualError(t, results[0].Err, "failed to write audit record: sink unavailable")
+	require.Equal(t, BatchInvalid, results[1].Status)
+	require.Empty(t, phoneBook.FindByPrefix(""))
+}
+
+func TestPhoneBook_UpsertMany(t *testing.T) {
+	phoneBook := New()
+	existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &existing)
+
+	results := phoneBook.UpsertMany(context.Background(), []Contact{
+		{Numbers: mobile("0123 456 789"), FirstName: "One", LastName: "Updated"},
+		{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"},
+		{Numbers: []PhoneNumber{{Type: Mobile, Number: "9876543210"}, {Type: Work, Number: "5432167890"}}, FirstName: "Two", LastName: "Updated"},
+		{Numbers: []PhoneNumber{{Type: Mobile, Number: "0123456789"}, {Type: Work, Number: "5432167890"}}, FirstName: "One", LastName: "Conflict"},
+		{FirstName: "Three", LastName: "Three"},
+		{ID: "unknown", Numbers: mobile("1111111111"), FirstName: "Four", LastName: "Four"},
+	}, BestEffort)
+
+	require.Equal(t, BatchResult{ID: existing.ID, Status: BatchOK, Replaced: true}, results[0])
+	require.Equal(t, BatchOK, results[1].Status)
+	require.False(t, results[1].Replaced)
+	require.Equal(t, BatchResult{ID: results[1].ID, Status: BatchOK, Replaced: true}, results[2])
+	require.Equal(t, BatchDuplicate, results[3].Status)
+	require.EqualError(t, results[3].Err, "contact already exists for new number 5432167890")
+	require.Equal(t, BatchInvalid, results[4].Status)
+	require.Equal(t, BatchNotFound, results[5].Status)
+	require.EqualError(t, results[5].Err, "contact not found for ID unknown")
+
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, "Updated", got.LastName)
+	got, ok = phoneBook.Get("5432167890")
+	require.True(t, ok)
+	require.Equal(t, results[1].ID, got.ID)
+	require.Equal(t, "Updated", got.LastName)
+
+	results = phoneBook.UpsertMany(context.Background(), []Contact{
+		{FirstName: "Three", LastName: "Three"},
+		{Numbers: mobile("1111111111"), FirstName: "Four", LastName: "Four"},
+	}, StopOnError)
+	require.Equal(t, BatchInvalid, results[0].Status)
+	require.Equal(t, BatchResult{Status: BatchSkipped}, results[1])
+}
+
+func TestPhoneBook_UpsertMany_auditError(t *testing.T) {
+	phoneBook := New(WithAuditSink(&limitedSink{remaining: 2}))
+	existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &existing)
+	sequence := phoneBook.Sequence()
+
+	// The first change can be audited but the second can not, so neither is kept
+	results := phoneBook.UpsertMany(context.Background(), []Contact{
+		{Numbers: mobile("0123456789"), FirstName: "One", LastName: "Updated"},
+		{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"},
+		{Numbers: mobile("123"), FirstName: "Three", LastName: "Three"},
+	}, BestEffort)
+
+	for _, result := range results[:2] {
+		require.Equal(t, BatchFailed, result.Status)
+		require.EqualError(t, result.Err, "failed to write audit record: sink unavailable")
+	}
+	require.Equal(t, BatchInvalid, results[2].Status)
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, existing, got)
+	_, ok = phoneBook.Get("9876543210")
+	require.False(t, ok)
+	require.Equal(t, sequence, phoneBook.Sequence())
+}
+
+func TestPhoneBook_DeleteMany(t *testing.T) {
+	phoneBook := New()
+	contact1 := Contact{Numbers: []PhoneNumber{{Type: Mobile, Number: "0123456789"}, {Type: Work, Number: "5432167890"}}, FirstName: "One", LastName: "One"}
+	contact2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &contact1)
+	add(t, phoneBook, &contact2)
+
+	results := phoneBook.DeleteMany(context.Background(), []string{"0123456789", "5432167890", "123", "9876543210"}, BestEffort)
+	require.Equal(t, []BatchResult{
+		{ID: contact1.ID, Status: BatchOK},
+		{Status: BatchNotFound},
+		{Status: BatchInvalid, Err: results[2].Err},
+		{ID: contact2.ID, Status: BatchOK},
+	}, results)
+	require.EqualError(t, results[2].Err, "phone number must contain 10 digits")
+	require.Empty(t, phoneBook.FindByPrefix(""))
+
+	add(t, phoneBook, &Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"})
+	results = phoneBook.DeleteMany(context.Background(), []string{"123", "9876543210"}, StopOnError)
+	require.Equal(t, BatchInvalid, results[0].Status)
+	require.Equal(t, BatchResult{Status: BatchSkipped}, results[1])
+	_, ok := phoneBook.Get("9876543210")
+	require.True(t, ok)
+}
+
+func TestPhoneBook_DeleteMany_auditError(t *testing.T) {
+	phoneBook := New(WithTrash(0), WithAuditSink(&limitedSink{remaining: 3}))
+	contact1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	contact2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &contact1)
+	add(t, phoneBook, &contact2)
+
+	// The first delete can be audited but the second can not, so neither is kept
+	results := phoneBook.DeleteMany(context.Background(), []string{"0123456789", "9876543210", "5432167890"}, BestEffort)
+	for _, result := range results[:2] {
+		require.Equal(t, BatchFailed, result.Status)
+		require.EqualError(t, result.Err, "failed to write audit record: sink unavailable")
+	}
+	require.Equal(t, BatchResult{Status: BatchNotFound}, results[2])
+	require.ElementsMatch(t, []Contact{contact1, contact2}, phoneBook.FindByPrefix(""))
+	require.Empty(t, phoneBook.ListTrash())
+}
+
+func TestPhoneBook_DeleteMany_trashRetention(t *testing.T) {
+	phoneBook := New(WithTrash(time.Hour))
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+	require.NoError(t, phoneBook.Delete(context.Background(), "0123456789"))
+	trashed := phoneBook.trash[contact.ID]
+	trashed.DeletedAt = time.Now().Add(-2 * time.Hour)
+	phoneBook.trash[contact.ID] = trashed
+
+	// Contacts whose retention has expired are purged, as by the other batches
+	phoneBook.DeleteMany(context.Background(), []string{"9876543210"}, BestEffort)
+	require.Empty(t, phoneBook.trash)
+}
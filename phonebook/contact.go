@@ -2,29 +2,256 @@ package phonebook
 
 import (
 	"fmt"
-	"regexp"
+	"net/mail"
+	"net/url"
 	"strings"
 )
 
+// NumberType describes the kind of phone number held by a contact.
+type NumberType string
+
+const (
+	Mobile NumberType = "mobile"
+	Home   NumberType = "home"
+	Work   NumberType = "work"
+	Fax    NumberType = "fax"
+	// Custom indicates a user defined number type, described by the label of the
+	// phone number.
+	Custom NumberType = "custom"
+)
+
+// PhoneNumber is a typed phone number belonging to a contact.
+type PhoneNumber struct {
+	Type   NumberType
+	Label  string
+	Number string
+}
+
+// ChannelType describes the purpose of an email address or website.
+type ChannelType string
+
+const (
+	Personal ChannelType = "personal"
+	Business ChannelType = "business"
+	Other    ChannelType = "other"
+)
+
+// Email is a typed email address belonging to a contact.
+type Email struct {
+	Type    ChannelType
+	Address string
+}
+
+// Website is a typed website belonging to a contact.
+type Website struct {
+	Type ChannelType
+	URL  string
+}
+
+// MessagingHandle is a contact's handle on a messaging service (e.g. a Signal
+// username).
+type MessagingHandle struct {
+	Service string
+	Handle  string
+}
+
 // Contact represents a contact found in a phone book.
 type Contact struct {
-	Number    string
+	// ID uniquely identifies the contact. It is assigned when the contact is
+	// added to a phone book and never changes.
+	ID string
+	// Version is set to one when the contact is added to a phone book, and is
+	// incremented each time the contact changes. Pass it to UpdateIfMatch or
+	// DeleteIfMatch to detect concurrent changes. It is ignored otherwise.
+	Version   uint64
+	Numbers   []PhoneNumber
 	FirstName string
 	LastName  string
 	Address   string
+	Emails    []Email
+	Websites  []Website
+	Messaging []MessagingHandle
+	// Tags are the labels of the groups the contact belongs to (e.g. suppliers).
+	// Each tag must be unique.
+	Tags []string
+	// Custom holds user defined fields by name (e.g. employee ID). See
+	// CustomField to declare how a phone book handles a custom field.
+	Custom map[string]string
 }
 
-// Validate checks that each field value is valid.
+// Validate checks that each field value is valid. The format of each phone
+// number is checked by the NumberPolicy of the phone book the contact is added
+// to.
 func (c Contact) Validate() error {
-	if matched, err := regexp.Match("^\\d{10}$", []byte(c.Number)); err != nil || !matched {
-		return fmt.Errorf("phone number must contain 10 digits")
+	if len(c.Numbers) == 0 {
+		return fmt.Errorf("at least one phone number required")
 	} else if c.FirstName == "" {
 		return fmt.Errorf("first name required")
 	} else if c.LastName == "" {
 		return fmt.Errorf("last name required")
-	} else if c.Address != "" && len(strings.Split(c.Address, ",")) != 5 {
+	} else if c.Address != "" && len(addressParts(c.Address)) != 5 {
 		return fmt.Errorf("address must be in the format '[street address], [city], [state/province], [zip code], [country]'")
 	}
 
+	for _, number := range c.Numbers {
+		if err := number.Validate(); err != nil {
+			return err
+		}
+	}
+
+	emails := map[string]bool{}
+	for _, email := range c.Emails {
+		if err := email.Validate(); err != nil {
+			return err
+		} else if key := emailKey(email.Address); emails[key] {
+			return fmt.Errorf("duplicate email address %s", email.Address)
+		} else {
+			emails[key] = true
+		}
+	}
+
+	for _, website := range c.Websites {
+		if err := website.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, handle := range c.Messaging {
+		if err := handle.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for name := range c.Custom {
+		if name == "" {
+			return fmt.Errorf("custom field name must not be empty")
+		}
+	}
+
+	tags := map[string]bool{}
+	for _, tag := range c.Tags {
+		if err := validateTag(tag); err != nil {
+			return err
+		} else if tags[tag] {
+			return fmt.Errorf("duplicate tag %s", tag)
+		}
+		tags[tag] = true
+	}
+
+	return nil
+}
+
+// Validate checks that the phone number has a valid type.
+func (n PhoneNumber) Validate() error {
+	if n.Number == "" {
+		return fmt.Errorf("phone number required")
+	}
+
+	switch n.Type {
+	case Mobile, Home, Work, Fax:
+	case Custom:
+		if n.Label == "" {
+			return fmt.Errorf("label required for custom phone number %s", n.Number)
+		}
+	default:
+		return fmt.Errorf("invalid phone number type '%s'", n.Type)
+	}
+
 	return nil
 }
+
+// Validate checks that the email address is a valid RFC 5322 address without a
+// display name, and that it has a valid type.
+func (e Email) Validate() error {
+	if addr, err := mail.ParseAddress(e.Address); err != nil || addr.Address != e.Address {
+		return fmt.Errorf("invalid email address '%s'", e.Address)
+	}
+	return validateChannelType(e.Type)
+}
+
+// Domain returns the domain of the email address.
+func (e Email) Domain() string {
+	return e.Address[strings.LastIndex(e.Address, "@")+1:]
+}
+
+// Validate checks that the website is an absolute http(s) URL with a valid type.
+func (w Website) Validate() error {
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid website URL '%s'", w.URL)
+	}
+	return validateChannelType(w.Type)
+}
+
+// Validate checks that both the service and handle are present.
+func (h MessagingHandle) Validate() error {
+	if h.Service == "" {
+		return fmt.Errorf("messaging service required")
+	} else if h.Handle == "" {
+		return fmt.Errorf("messaging handle required for service %s", h.Service)
+	}
+	return nil
+}
+
+// HasTag reports whether the contact has the specified tag.
+func (c Contact) HasTag(tag string) bool {
+	return contains(c.Tags, tag)
+}
+
+func validateTag(tag string) error {
+	if strings.TrimSpace(tag) == "" {
+		return fmt.Errorf("tag must not be empty")
+	}
+	return nil
+}
+
+func validateChannelType(t ChannelType) error {
+	switch t {
+	case Personal, Business, Other:
+		return nil
+	default:
+		return fmt.Errorf("invalid channel type '%s'", t)
+	}
+}
+
+// NumberStrings returns the numbers of each of the contact's phone numbers.
+func (c Contact) NumberStrings() []string {
+	numbers := make([]string, len(c.Numbers))
+	for i, number := range c.Numbers {
+		numbers[i] = number.Number
+	}
+	return numbers
+}
+
+// clone returns a deep copy of the contact so that callers can not modify the
+// contact stored within the phone book.
+func (c Contact) clone() Contact {
+	c.Numbers = append([]PhoneNumber(nil), c.Numbers...)
+	c.Emails = append([]Email(nil), c.Emails...)
+	c.Websites = append([]Website(nil), c.Websites...)
+	c.Messaging = append([]MessagingHandle(nil), c.Messaging...)
+	c.Tags = append([]string(nil), c.Tags...)
+	if c.Custom != nil {
+		custom := make(map[string]string, len(c.Custom))
+		for name, value := range c.Custom {
+			custom[name] = value
+		}
+		c.Custom = custom
+	}
+	return c
+}
+
+// addressParts returns the comma separated parts of an address, with the spaces
+// around each part removed. A valid address has five parts.
+func addressParts(address string) []string {
+	parts := strings.Split(address, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// emailKey returns the key used to compare and index an email address. Email
+// addresses are case-insensitive in practice, so they are compared in lowercase.
+func emailKey(address string) string {
+	return strings.ToLower(address)
+}
@@ -15,56 +15,196 @@ func TestContact_Validate(t *testing.T) {
 		{
 			name: "valid contact",
 			contact: Contact{
-				Number:    "0123456789",
+				Numbers:   mobile("0123456789"),
 				FirstName: "foo",
 				LastName:  "bar",
 			},
 		},
 		{
-			name: "phone number contains invalid chars",
+			name: "first name empty",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "",
+				LastName:  "bar",
+			},
+			wantErr: "first name required",
+		},
+		{
+			name: "last name empty",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "",
+			},
+			wantErr: "last name required",
+		},
+		{
+			name: "invalid address format",
 			contact: Contact{
-				Number:    "0123K56P89",
+				Numbers:   mobile("0123456789"),
 				FirstName: "foo",
 				LastName:  "bar",
+				Address:   "11 Fake St, Fake City, Fake State, 1111",
 			},
-			wantErr: "phone number must contain 10 digits",
+			wantErr: "address must be in the format '[street address], [city], [state/province], [zip code], [country]'",
 		},
 		{
-			name: "phone number not length 10",
+			name: "valid contact with multiple numbers",
 			contact: Contact{
-				Number:    "012345678",
+				Numbers: []PhoneNumber{
+					{Type: Mobile, Number: "0123456789"},
+					{Type: Work, Number: "9876543210"},
+					{Type: Custom, Label: "holiday house", Number: "5555555555"},
+				},
 				FirstName: "foo",
 				LastName:  "bar",
 			},
-			wantErr: "phone number must contain 10 digits",
 		},
 		{
-			name: "first name empty",
+			name: "phone number missing",
 			contact: Contact{
-				Number:    "0123456789",
-				FirstName: "",
+				FirstName: "foo",
 				LastName:  "bar",
 			},
-			wantErr: "first name required",
+			wantErr: "at least one phone number required",
 		},
 		{
-			name: "last name empty",
+			name: "phone number empty",
 			contact: Contact{
-				Number:    "0123456789",
+				Numbers:   []PhoneNumber{{Type: Mobile}},
 				FirstName: "foo",
-				LastName:  "",
+				LastName:  "bar",
 			},
-			wantErr: "last name required",
+			wantErr: "phone number required",
 		},
 		{
-			name: "invalid address format",
+			name: "custom phone number without label",
 			contact: Contact{
-				Number:    "0123456789",
+				Numbers:   []PhoneNumber{{Type: Custom, Number: "0123456789"}},
 				FirstName: "foo",
 				LastName:  "bar",
-				Address:   "11 Fake St, Fake City, Fake State, 1111",
 			},
-			wantErr: "address must be in the format '[street address], [city], [state/province], [zip code], [country]'",
+			wantErr: "label required for custom phone number 0123456789",
+		},
+		{
+			name: "invalid phone number type",
+			contact: Contact{
+				Numbers:   []PhoneNumber{{Type: "pager", Number: "0123456789"}},
+				FirstName: "foo",
+				LastName:  "bar",
+			},
+			wantErr: "invalid phone number type 'pager'",
+		},
+		{
+			name: "valid contact with channels",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Emails:    []Email{{Type: Personal, Address: "foo@example.com"}, {Type: Business, Address: "foo@work.example.com"}},
+				Websites:  []Website{{Type: Business, URL: "https://example.com/foo"}},
+				Messaging: []MessagingHandle{{Service: "signal", Handle: "foo.01"}},
+			},
+		},
+		{
+			name: "invalid email address",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Emails:    []Email{{Type: Personal, Address: "foo.example.com"}},
+			},
+			wantErr: "invalid email address 'foo.example.com'",
+		},
+		{
+			name: "email address with display name",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Emails:    []Email{{Type: Personal, Address: "Foo <foo@example.com>"}},
+			},
+			wantErr: "invalid email address 'Foo <foo@example.com>'",
+		},
+		{
+			name: "duplicate email address",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Emails:    []Email{{Type: Personal, Address: "foo@example.com"}, {Type: Business, Address: "FOO@example.com"}},
+			},
+			wantErr: "duplicate email address FOO@example.com",
+		},
+		{
+			name: "invalid email type",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Emails:    []Email{{Address: "foo@example.com"}},
+			},
+			wantErr: "invalid channel type ''",
+		},
+		{
+			name: "invalid website URL",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Websites:  []Website{{Type: Personal, URL: "example.com"}},
+			},
+			wantErr: "invalid website URL 'example.com'",
+		},
+		{
+			name: "messaging service empty",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Messaging: []MessagingHandle{{Handle: "foo"}},
+			},
+			wantErr: "messaging service required",
+		},
+		{
+			name: "messaging handle empty",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Messaging: []MessagingHandle{{Service: "signal"}},
+			},
+			wantErr: "messaging handle required for service signal",
+		},
+		{
+			name: "empty tag",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Tags:      []string{"suppliers", ""},
+			},
+			wantErr: "tag must not be empty",
+		},
+		{
+			name: "duplicate tag",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Tags:      []string{"suppliers", "suppliers"},
+			},
+			wantErr: "duplicate tag suppliers",
+		},
+		{
+			name: "empty custom field name",
+			contact: Contact{
+				Numbers:   mobile("0123456789"),
+				FirstName: "foo",
+				LastName:  "bar",
+				Custom:    map[string]string{"": "value"},
+			},
+			wantErr: "custom field name must not be empty",
 		},
 	}
 
@@ -0,0 +1,115 @@
+package phonebook
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CustomField declares how a phone book handles a custom field of its contacts.
+// Custom fields that are not declared may still be stored on contacts, but they
+// are not indexed or validated.
+type CustomField struct {
+	Name string
+	// Indexed makes the field searchable through Find and FindByCustomField in
+	// O(1) time.
+	Indexed bool
+	// Unique requires each value of the field to belong to at most one contact.
+	// Unique fields are always indexed.
+	Unique bool
+	// Required requires every contact to have a non-empty value for the field.
+	Required bool
+}
+
+// FindByCustomField returns all contacts with the specified value for a custom
+// field. Fields that are not indexed are searched by checking every contact.
+func (p *PhoneBook) FindByCustomField(name string, value string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.customFieldIndexed(name) {
+		if found, ok := p.indexes.Get(p.customIndex(name, value)); ok {
+			return clones(found)
+		}
+		return []Contact{}
+	}
+
+	found := []Contact{}
+	for _, contact := range p.contacts {
+		if v, ok := contact.Custom[name]; ok && v == value {
+			found = append(found, contact.clone())
+		}
+	}
+	return found
+}
+
+// validateCustomFields checks that the contact has a value for each required
+// custom field.
+func (p *PhoneBook) validateCustomFields(contact Contact) error {
+	for _, name := range p.customFieldNames() {
+		if p.customFields[name].Required && contact.Custom[name] == "" {
+			return fmt.Errorf("custom field %s required", name)
+		}
+	}
+	return nil
+}
+
+func (p *PhoneBook) customFieldIndexed(name string) bool {
+	field, ok := p.customFields[name]
+	return ok && (field.Indexed || field.Unique)
+}
+
+// customFieldNames returns the names of the declared custom fields in sorted
+// order, so that validation errors are deterministic.
+func (p *PhoneBook) customFieldNames() []string {
+	names := make([]string, 0, len(p.customFields))
+	for name := range p.customFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// customIndex returns the name of the index and the key used to look up a value
+// of an indexed custom field. Unique fields each have their own unique index,
+// while other indexed fields share a single index.
+func (p *PhoneBook) customIndex(name string, value string) (string, string) {
+	if p.customFields[name].Unique {
+		return customIndexName(name), value
+	}
+	return indexCustom, customKey(name, value)
+}
+
+// customKeys returns the keys of each of the contact's indexed custom fields for
+// the shared custom field index.
+func (p *PhoneBook) customKeys(contact *Contact) []string {
+	var keys []string
+	for name, value := range contact.Custom {
+		if field, ok := p.customFields[name]; ok && field.Indexed && !field.Unique {
+			keys = append(keys, customKey(name, value))
+		}
+	}
+	return keys
+}
+
+// customValueKeys returns a function that returns the value of the named custom
+// field as the only key of a contact.
+func customValueKeys(name string) func(contact *Contact) []string {
+	return func(contact *Contact) []string {
+		if value := contact.Custom[name]; value != "" {
+			return []string{value}
+		}
+		return nil
+	}
+}
+
+// customIndexName returns the name of the unique index of a custom field.
+func customIndexName(name string) string {
+	return "custom:" + name
+}
+
+// customKey returns the index key of a custom field value. The field name and
+// value are separated by a null byte, which can not be confused with the
+// contents of either.
+func customKey(name string, value string) string {
+	return name + "\x00" + value
+}
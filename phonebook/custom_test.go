@@ -0,0 +1,112 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_FindByCustomField(t *testing.T) {
+	phoneBook := New(WithCustomField(CustomField{Name: "cost_center", Indexed: true}))
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Custom: map[string]string{"cost_center": "cc-100", "manager": "Foo"}}
+	want2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Custom: map[string]string{"cost_center": "cc-100"}}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Custom: map[string]string{"cost_center": "cc-200"}}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
+
+	tests := []struct {
+		name  string
+		field string
+		value string
+		want  []Contact
+	}{
+		{
+			name:  "indexed field",
+			field: "cost_center",
+			value: "cc-100",
+			want:  []Contact{want1, want2},
+		},
+		{
+			name:  "field that is not declared",
+			field: "manager",
+			value: "Foo",
+			want:  []Contact{want1},
+		},
+		{
+			name:  "not found",
+			field: "cost_center",
+			value: "random",
+			want:  []Contact{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ElementsMatch(t, tt.want, phoneBook.FindByCustomField(tt.field, tt.value))
+		})
+	}
+
+	require.NoError(t, phoneBook.Delete(context.Background(), want1.Numbers[0].Number))
+	require.Equal(t, []Contact{want2}, phoneBook.FindByCustomField("cost_center", "cc-100"))
+}
+
+func TestPhoneBook_Find_customField(t *testing.T) {
+	phoneBook := New(WithCustomField(CustomField{Name: "employee_id", Indexed: true}))
+	want := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Custom: map[string]string{"employee_id": "E-42"}}
+	dummy := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Custom: map[string]string{"other": "E-42"}}
+	add(t, phoneBook, &want)
+	add(t, phoneBook, &dummy)
+
+	require.Equal(t, []Contact{want}, phoneBook.Find("E-42"))
+}
+
+func TestPhoneBook_customFieldRequired(t *testing.T) {
+	phoneBook := New(WithCustomField(CustomField{Name: "employee_id", Required: true}))
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+
+	_, err := phoneBook.Add(context.Background(), contact)
+	require.EqualError(t, err, "custom field employee_id required")
+
+	contact.Custom = map[string]string{"employee_id": "E-1"}
+	add(t, phoneBook, &contact)
+
+	contact.Custom = map[string]string{"employee_id": ""}
+	require.EqualError(t, phoneBook.Update(context.Background(), contact.Numbers[0].Number, contact), "custom field employee_id required")
+}
+
+func TestPhoneBook_customFieldUnique(t *testing.T) {
+	phoneBook := New(WithCustomField(CustomField{Name: "employee_id", Unique: true}))
+	existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Custom: map[string]string{"employee_id": "E-1"}}
+	other := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Custom: map[string]string{"employee_id": "E-2"}}
+	add(t, phoneBook, &existing)
+	add(t, phoneBook, &other)
+
+	_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Custom: map[string]string{"employee_id": "E-1"}})
+	require.Equal(t, &ConstraintError{Constraint: "custom:employee_id", Key: "E-1", ContactID: existing.ID}, err)
+	require.EqualError(t, err, "unique constraint custom:employee_id violated: E-1 already exists")
+
+	other.Custom = map[string]string{"employee_id": "E-1"}
+	err = phoneBook.Update(context.Background(), other.Numbers[0].Number, other)
+	require.Equal(t, &ConstraintError{Constraint: "custom:employee_id", Key: "E-1", ContactID: existing.ID}, err)
+
+	// Contacts without a value for the field do not conflict
+	add(t, phoneBook, &Contact{Numbers: mobile("1111111111"), FirstName: "Four", LastName: "Four"})
+	add(t, phoneBook, &Contact{Numbers: mobile("2222222222"), FirstName: "Five", LastName: "Five", Custom: map[string]string{"employee_id": ""}})
+
+	// Unique fields are indexed
+	require.Equal(t, []Contact{existing}, phoneBook.FindByCustomField("employee_id", "E-1"))
+}
+
+func TestPhoneBook_customFieldNotShared(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Custom: map[string]string{"employee_id": "E-1"}}
+	add(t, phoneBook, &contact)
+
+	// Modifying the custom fields of a contact does not modify the stored contact
+	contact.Custom["employee_id"] = "E-2"
+	got, ok := phoneBook.Get(contact.Numbers[0].Number)
+	require.True(t, ok)
+	require.Equal(t, "E-1", got.Custom["employee_id"])
+}
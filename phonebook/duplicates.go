@@ -0,0 +1,387 @@
+package phonebook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultDuplicateConfidence is the minimum confidence of the duplicates found
+// by FindDuplicates if DuplicateOptions does not set one.
+const DefaultDuplicateConfidence = 0.7
+
+// minNameSimilarity is the similarity below which two names are considered to
+// belong to different people, whatever else the contacts have in common.
+const minNameSimilarity = 0.6
+
+// Weights of each kind of evidence that two contacts are the same person, which
+// add up to one.
+const (
+	nameWeight    = 0.5
+	addressWeight = 0.3
+	emailWeight   = 0.2
+)
+
+// DuplicateReason describes why contacts were considered to be duplicates.
+type DuplicateReason string
+
+const (
+	// ReasonSimilarName indicates that the contacts have the same or similarly
+	// spelt names.
+	ReasonSimilarName DuplicateReason = "similar_name"
+	// ReasonSameAddress indicates that the contacts have the same address.
+	ReasonSameAddress DuplicateReason = "same_address"
+	// ReasonSharedEmail indicates that the contacts share an email address.
+	ReasonSharedEmail DuplicateReason = "shared_email"
+)
+
+// DuplicateOptions configures FindDuplicates.
+type DuplicateOptions struct {
+	// MinConfidence is the minimum confidence, between zero and one, for two
+	// contacts to be considered duplicates. DefaultDuplicateConfidence is used
+	// if zero.
+	MinConfidence float64
+}
+
+// DuplicateGroup is a group of contacts that are likely to be the same person.
+type DuplicateGroup struct {
+	// Contacts are the contacts in the group, sorted by ID.
+	Contacts []Contact
+	// Confidence is the lowest confidence of the matches that joined the group,
+	// between zero and one.
+	Confidence float64
+	// Reasons are the reasons the contacts were matched, in order of weight.
+	Reasons []DuplicateReason
+}
+
+// FindDuplicates returns groups of contacts that are likely to be the same
+// person, such as a contact imported twice with different numbers, sorted by
+// confidence with the most likely duplicates first.
+//
+// Each pair of contacts is scored by the similarity of their names, and whether
+// they have the same address or share an email address. Names that are spelt
+// slightly differently (e.g. Jon and John) are similar, while contacts with
+// dissimilar names are never duplicates. A contact with the same name and
+// address as another scores 0.8, and 1 if they also share an email address.
+// Contacts are grouped with every contact they match, so a group may hold more
+// than two contacts. Only contacts that share a first name, last name, address
+// or email address are compared, which are found with the phone book's indexes.
+func (p *PhoneBook) FindDuplicates(opts DuplicateOptions) []DuplicateGroup {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	minConfidence := opts.MinConfidence
+	if minConfidence == 0 {
+		minConfidence = DefaultDuplicateConfidence
+	}
+
+	addresses := map[string][]*Contact{}
+	for _, contact := range p.contacts {
+		if contact.Address != "" {
+			key := normalizeAddress(contact.Address)
+			addresses[key] = append(addresses[key], contact)
+		}
+	}
+
+	groups := newDuplicateSets()
+	for _, contact := range p.contacts {
+		for _, candidate := range p.duplicateCandidates(contact, addresses) {
+			if candidate.ID <= contact.ID {
+				continue
+			}
+			confidence, reasons := matchContacts(contact, candidate)
+			if confidence >= minConfidence {
+				groups.join(contact, candidate, confidence, reasons)
+			}
+		}
+	}
+	return groups.list()
+}
+
+// Merge combines the contacts that own the other numbers into the contact that
+// owns the primary number, and deletes the other contacts along with their
+// history, even if the trash is enabled. Conflicting fields are resolved as
+// follows:
+//
+//   - The primary contact's name and address are kept, and the first other
+//     contact with an address fills in a missing address.
+//   - Numbers, email addresses, websites, messaging handles and tags are
+//     combined, with the primary contact's first and duplicates removed. Email
+//     addresses are compared case-insensitively.
+//   - The primary contact's custom fields are kept, and other contacts fill in
+//     missing fields in order.
+//
+// The merged contact keeps the primary contact's ID and is checked as by Update.
+// Either every change is made or none are, and the merged contact is returned.
+func (p *PhoneBook) Merge(ctx context.Context, primary string, others ...string) (Contact, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(others) == 0 {
+		return Contact{}, fmt.Errorf("at least one contact to merge required")
+	}
+
+	existing, ok := p.contactByNumber(primary)
+	if !ok {
+		return Contact{}, fmt.Errorf("contact not found for number %s", primary)
+	}
+
+	merging := make([]*Contact, 0, len(others))
+	seen := map[string]bool{existing.ID: true}
+	for _, number := range others {
+		contact, ok := p.contactByNumber(number)
+		if !ok {
+			return Contact{}, fmt.Errorf("contact not found for number %s", number)
+		} else if seen[contact.ID] {
+			return Contact{}, fmt.Errorf("contact for number %s is listed more than once", number)
+		}
+		seen[contact.ID] = true
+		merging = append(merging, contact)
+	}
+
+	merged := mergeContacts(existing, merging)
+	tx := &Tx{p: p, ctx: ctx}
+	err := p.atomically(tx, func() error {
+		// The other contacts are deleted first to free their numbers
+		for _, contact := range merging {
+			if err := p.apply(ctx, change{before: contact, permanent: true}); err != nil {
+				return err
+			}
+		}
+		return p.update(ctx, existing, merged)
+	})
+	if err != nil {
+		return Contact{}, err
+	}
+	return p.contacts[existing.ID].clone(), nil
+}
+
+// mergeContacts returns the primary contact with the fields of the other
+// contacts combined into it, as described by Merge.
+func mergeContacts(primary *Contact, others []*Contact) Contact {
+	merged := primary.clone()
+	for _, other := range others {
+		if merged.Address == "" {
+			merged.Address = other.Address
+		}
+		merged.Numbers = append(merged.Numbers, other.Numbers...)
+		merged.Emails = unionBy(merged.Emails, other.Emails, func(e Email) string { return emailKey(e.Address) })
+		merged.Websites = unionBy(merged.Websites, other.Websites, func(w Website) string { return w.URL })
+		merged.Messaging = unionBy(merged.Messaging, other.Messaging, func(h MessagingHandle) string { return h.Service + "\x00" + h.Handle })
+		merged.Tags = unionBy(merged.Tags, other.Tags, func(tag string) string { return tag })
+		for name, value := range other.Custom {
+			if _, ok := merged.Custom[name]; ok {
+				continue
+			}
+			if merged.Custom == nil {
+				merged.Custom = map[string]string{}
+			}
+			merged.Custom[name] = value
+		}
+	}
+	return merged
+}
+
+// unionBy appends the values that are not already in values, compared by key.
+func unionBy[T any](values []T, more []T, key func(T) string) []T {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		seen[key(value)] = true
+	}
+	for _, value := range more {
+		if k := key(value); !seen[k] {
+			seen[k] = true
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// duplicateCandidates returns the contacts that share a first name, last name,
+// address or email address with the contact, which may include the contact
+// itself.
+func (p *PhoneBook) duplicateCandidates(contact *Contact, addresses map[string][]*Contact) []*Contact {
+	var candidates []*Contact
+	if found, ok := p.indexes.Get(indexFirstName, contact.FirstName); ok {
+		candidates = append(candidates, found...)
+	}
+	if found, ok := p.indexes.Get(indexLastName, contact.LastName); ok {
+		candidates = append(candidates, found...)
+	}
+	if contact.Address != "" {
+		candidates = append(candidates, addresses[normalizeAddress(contact.Address)]...)
+	}
+	for _, key := range emailKeys(contact) {
+		if found, ok := p.indexes.Get(indexEmail, key); ok {
+			candidates = append(candidates, found...)
+		}
+	}
+	return candidates
+}
+
+// matchContacts returns the confidence that two contacts are the same person,
+// and the reasons they match.
+func matchContacts(a *Contact, b *Contact) (float64, []DuplicateReason) {
+	similarity := nameSimilarity(a, b)
+	if similarity < minNameSimilarity {
+		return 0, nil
+	}
+
+	confidence := nameWeight * similarity
+	reasons := []DuplicateReason{ReasonSimilarName}
+	if a.Address != "" && normalizeAddress(a.Address) == normalizeAddress(b.Address) {
+		confidence += addressWeight
+		reasons = append(reasons, ReasonSameAddress)
+	}
+	if sharesEmail(a, b) {
+		confidence += emailWeight
+		reasons = append(reasons, ReasonSharedEmail)
+	}
+	return confidence, reasons
+}
+
+// nameSimilarity returns the similarity of the contacts' full names, between zero
+// and one, as one minus the edit distance of the names relative to the length of
+// the longer name. Names are compared case-insensitively.
+func nameSimilarity(a *Contact, b *Contact) float64 {
+	x := []rune(strings.ToLower(a.FirstName + " " + a.LastName))
+	y := []rune(strings.ToLower(b.FirstName + " " + b.LastName))
+	longest := len(x)
+	if len(y) > longest {
+		longest = len(y)
+	}
+	return 1 - float64(editDistance(x, y))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance between a and b, which is the
+// number of single character insertions, deletions and substitutions needed to
+// change a into b.
+func editDistance(a []rune, b []rune) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			next := diagonal + cost
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if row[j-1]+1 < next {
+				next = row[j-1] + 1
+			}
+			diagonal, row[j] = row[j], next
+		}
+	}
+	return row[len(b)]
+}
+
+func sharesEmail(a *Contact, b *Contact) bool {
+	keys := emailKeys(a)
+	for _, key := range emailKeys(b) {
+		if contains(keys, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAddress returns the form used to compare addresses, ignoring case and
+// spacing.
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.Join(strings.Fields(address), " "))
+}
+
+// duplicateSets clusters matched contacts into groups with a disjoint set, so
+// that contacts matched through a common contact are in the same group.
+type duplicateSets struct {
+	parents  map[string]string
+	contacts map[string]*Contact
+	// confidence and reasons are held by the root of each group.
+	confidence map[string]float64
+	reasons    map[string]map[DuplicateReason]bool
+}
+
+func newDuplicateSets() *duplicateSets {
+	return &duplicateSets{
+		parents:    map[string]string{},
+		contacts:   map[string]*Contact{},
+		confidence: map[string]float64{},
+		reasons:    map[string]map[DuplicateReason]bool{},
+	}
+}
+
+// find returns the root of the contact's group, adding the contact to a group
+// of its own if it is not in one.
+func (s *duplicateSets) find(contact *Contact) string {
+	if _, ok := s.parents[contact.ID]; !ok {
+		s.parents[contact.ID] = contact.ID
+		s.contacts[contact.ID] = contact
+		s.confidence[contact.ID] = 1
+		s.reasons[contact.ID] = map[DuplicateReason]bool{}
+	}
+	id := contact.ID
+	for s.parents[id] != id {
+		s.parents[id] = s.parents[s.parents[id]]
+		id = s.parents[id]
+	}
+	return id
+}
+
+// join joins the groups of two matched contacts.
+func (s *duplicateSets) join(a *Contact, b *Contact, confidence float64, reasons []DuplicateReason) {
+	root, other := s.find(a), s.find(b)
+	if root != other {
+		s.parents[other] = root
+		if s.confidence[other] < s.confidence[root] {
+			s.confidence[root] = s.confidence[other]
+		}
+		for reason := range s.reasons[other] {
+			s.reasons[root][reason] = true
+		}
+		delete(s.confidence, other)
+		delete(s.reasons, other)
+	}
+	if confidence < s.confidence[root] {
+		s.confidence[root] = confidence
+	}
+	for _, reason := range reasons {
+		s.reasons[root][reason] = true
+	}
+}
+
+// list returns each group sorted by confidence, then by the ID of the group's
+// first contact.
+func (s *duplicateSets) list() []DuplicateGroup {
+	members := map[string][]Contact{}
+	for id, contact := range s.contacts {
+		root := s.find(contact)
+		members[root] = append(members[root], s.contacts[id].clone())
+	}
+
+	groups := make([]DuplicateGroup, 0, len(members))
+	for root, contacts := range members {
+		sort.Slice(contacts, func(i, j int) bool { return contacts[i].ID < contacts[j].ID })
+		group := DuplicateGroup{Contacts: contacts, Confidence: s.confidence[root]}
+		for _, reason := range []DuplicateReason{ReasonSimilarName, ReasonSameAddress, ReasonSharedEmail} {
+			if s.reasons[root][reason] {
+				group.Reasons = append(group.Reasons, reason)
+			}
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Confidence != groups[j].Confidence {
+			return groups[i].Confidence > groups[j].Confidence
+		}
+		return groups[i].Contacts[0].ID < groups[j].Contacts[0].ID
+	})
+	return groups
+}
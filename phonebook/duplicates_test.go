@@ -0,0 +1,224 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_FindDuplicates(t *testing.T) {
+	phoneBook := New()
+	jon := Contact{Numbers: mobile("0111111111"), FirstName: "Jon", LastName: "Smith", Address: newAddress("Foo City"), Emails: []Email{{Type: Personal, Address: "jon@example.com"}}}
+	john := Contact{Numbers: mobile("0222222222"), FirstName: "John", LastName: "Smith", Address: "1 foo st,  Foo City, Foo State, 1111, Foo Country"}
+	jonEmail := Contact{Numbers: mobile("0333333333"), FirstName: "Jon", LastName: "Smith", Emails: []Email{{Type: Business, Address: "JON@example.com"}}}
+	mary := Contact{Numbers: mobile("0444444444"), FirstName: "Mary", LastName: "Smith", Address: newAddress("Foo City")}
+	ann := Contact{Numbers: mobile("0555555555"), FirstName: "Ann", LastName: "Lee"}
+	annOther := Contact{Numbers: mobile("0666666666"), FirstName: "Ann", LastName: "Lee"}
+	unrelated := Contact{Numbers: mobile("0777777777"), FirstName: "Other", LastName: "Person", Emails: []Email{{Type: Personal, Address: "jon@example.com"}}}
+	for _, contact := range []*Contact{&jon, &john, &jonEmail, &mary, &ann, &annOther} {
+		add(t, phoneBook, contact)
+	}
+	_, err := phoneBook.Add(context.Background(), unrelated)
+	require.NoError(t, err)
+
+	// Jon is matched to John by address and to the other Jon by email, while a
+	// family member at the same address and a shared name alone are not enough
+	groups := phoneBook.FindDuplicates(DuplicateOptions{})
+	require.Len(t, groups, 1)
+	require.ElementsMatch(t, []Contact{jon, john, jonEmail}, groups[0].Contacts)
+	require.InDelta(t, 0.7, groups[0].Confidence, 1e-9)
+	require.Equal(t, []DuplicateReason{ReasonSimilarName, ReasonSameAddress, ReasonSharedEmail}, groups[0].Reasons)
+
+	groups = phoneBook.FindDuplicates(DuplicateOptions{MinConfidence: 0.75})
+	require.Len(t, groups, 1)
+	require.ElementsMatch(t, []Contact{jon, john}, groups[0].Contacts)
+	require.InDelta(t, 0.75, groups[0].Confidence, 1e-9)
+	require.Equal(t, []DuplicateReason{ReasonSimilarName, ReasonSameAddress}, groups[0].Reasons)
+
+	// Groups are sorted by confidence
+	groups = phoneBook.FindDuplicates(DuplicateOptions{MinConfidence: 0.5})
+	require.Len(t, groups, 2)
+	require.ElementsMatch(t, []Contact{jon, john, jonEmail, mary}, groups[0].Contacts)
+	require.InDelta(t, 0.6, groups[0].Confidence, 1e-9)
+	require.ElementsMatch(t, []Contact{ann, annOther}, groups[1].Contacts)
+	require.InDelta(t, 0.5, groups[1].Confidence, 1e-9)
+	require.Equal(t, []DuplicateReason{ReasonSimilarName}, groups[1].Reasons)
+
+	require.Empty(t, New().FindDuplicates(DuplicateOptions{}))
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want float64
+	}{
+		{a: "Jon", b: "Jon", want: 1},
+		{a: "Jon", b: "jon", want: 1},
+		{a: "Jon", b: "John", want: 0.9},
+		{a: "Jon", b: "Mary", want: 0.6},
+		{a: "Zoë", b: "Zoe", want: 8.0 / 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			a := &Contact{FirstName: tt.a, LastName: "Smith"}
+			b := &Contact{FirstName: tt.b, LastName: "Smith"}
+			require.InDelta(t, tt.want, nameSimilarity(a, b), 1e-9)
+			require.InDelta(t, tt.want, nameSimilarity(b, a), 1e-9)
+		})
+	}
+}
+
+func TestPhoneBook_Merge(t *testing.T) {
+	phoneBook := New(WithTrash(0))
+	primary := Contact{
+		Numbers:   mobile("0111111111"),
+		FirstName: "Jon",
+		LastName:  "Smith",
+		Emails:    []Email{{Type: Personal, Address: "jon@example.com"}},
+		Tags:      []string{"suppliers"},
+		Custom:    map[string]string{"employee_id": "1"},
+	}
+	john := Contact{
+		Numbers:   []PhoneNumber{{Type: Work, Number: "0222222222"}, {Type: Fax, Number: "0222222223"}},
+		FirstName: "John",
+		LastName:  "Smith",
+		Address:   newAddress("Foo City"),
+		Emails:    []Email{{Type: Business, Address: "JON@example.com"}, {Type: Business, Address: "john@example.com"}},
+		Websites:  []Website{{Type: Business, URL: "https://example.com"}},
+		Tags:      []string{"vendors", "suppliers"},
+		Custom:    map[string]string{"employee_id": "2", "team": "sales"},
+	}
+	jonathan := Contact{
+		Numbers:   mobile("0333333333"),
+		FirstName: "Jonathan",
+		LastName:  "Smith",
+		Address:   newAddress("Bar City"),
+		Messaging: []MessagingHandle{{Service: "signal", Handle: "jon"}},
+		Custom:    map[string]string{"team": "support"},
+	}
+	for _, contact := range []*Contact{&primary, &john, &jonathan} {
+		add(t, phoneBook, contact)
+	}
+	sub, err := phoneBook.Subscribe(Filter{})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	merged, err := phoneBook.Merge(context.Background(), "0111111111", "0222222222", "0333333333")
+	require.NoError(t, err)
+
+	// The primary contact's fields win, and the others fill in what is missing
+	want := Contact{
+		ID:      primary.ID,
+		Version: 2,
+		Numbers: []PhoneNumber{
+			{Type: Mobile, Number: "0111111111"},
+			{Type: Work, Number: "0222222222"},
+			{Type: Fax, Number: "0222222223"},
+			{Type: Mobile, Number: "0333333333"},
+		},
+		FirstName: "Jon",
+		LastName:  "Smith",
+		Address:   newAddress("Foo City"),
+		Emails:    []Email{{Type: Personal, Address: "jon@example.com"}, {Type: Business, Address: "john@example.com"}},
+		Websites:  []Website{{Type: Business, URL: "https://example.com"}},
+		Messaging: []MessagingHandle{{Service: "signal", Handle: "jon"}},
+		Tags:      []string{"suppliers", "vendors"},
+		Custom:    map[string]string{"employee_id": "1", "team": "sales"},
+	}
+	require.Equal(t, want, merged)
+	for _, number := range []string{"0111111111", "0222222223", "0333333333"} {
+		got, ok := phoneBook.Get(number)
+		require.True(t, ok)
+		require.Equal(t, want, got)
+	}
+	require.Equal(t, []Contact{want}, phoneBook.FindByCity("Foo City"))
+	require.Empty(t, phoneBook.FindByCity("Bar City"))
+
+	// The other contacts are deleted permanently, bypassing the trash
+	require.Empty(t, phoneBook.ListTrash())
+	_, err = phoneBook.HistoryByID(john.ID)
+	require.Error(t, err)
+	_, ok := phoneBook.GetByID(jonathan.ID)
+	require.False(t, ok)
+
+	var types []EventType
+	for i := 0; i < 3; i++ {
+		event := <-sub.Events()
+		types = append(types, event.Type)
+	}
+	require.Equal(t, []EventType{Deleted, Deleted, Updated}, types)
+	require.Empty(t, phoneBook.FindDuplicates(DuplicateOptions{}))
+}
+
+func TestPhoneBook_Merge_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		primary string
+		others  []string
+		wantErr string
+	}{
+		{
+			name:    "no others",
+			primary: "0111111111",
+			wantErr: "at least one contact to merge required",
+		},
+		{
+			name:    "primary not found",
+			primary: "0999999999",
+			others:  []string{"0222222222"},
+			wantErr: "contact not found for number 0999999999",
+		},
+		{
+			name:    "other not found",
+			primary: "0111111111",
+			others:  []string{"0999999999"},
+			wantErr: "contact not found for number 0999999999",
+		},
+		{
+			name:    "other is primary",
+			primary: "0111111111",
+			others:  []string{"0111111112"},
+			wantErr: "contact for number 0111111112 is listed more than once",
+		},
+		{
+			name:    "other repeated",
+			primary: "0111111111",
+			others:  []string{"0222222222", "0222222222"},
+			wantErr: "contact for number 0222222222 is listed more than once",
+		},
+		{
+			name:    "audit error",
+			opts:    []Option{WithAuditSink(&limitedSink{remaining: 3})},
+			primary: "0111111111",
+			others:  []string{"0222222222"},
+			wantErr: "failed to write audit record: sink unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phoneBook := New(tt.opts...)
+			primary := Contact{Numbers: []PhoneNumber{{Type: Mobile, Number: "0111111111"}, {Type: Home, Number: "0111111112"}}, FirstName: "Jon", LastName: "Smith"}
+			other := Contact{Numbers: mobile("0222222222"), FirstName: "John", LastName: "Smith", Custom: map[string]string{"team": "sales"}}
+			add(t, phoneBook, &primary)
+			add(t, phoneBook, &other)
+			sequence := phoneBook.Sequence()
+
+			_, err := phoneBook.Merge(context.Background(), tt.primary, tt.others...)
+			require.EqualError(t, err, tt.wantErr)
+
+			// Nothing is changed
+			got, ok := phoneBook.Get("0111111111")
+			require.True(t, ok)
+			require.Equal(t, primary, got)
+			got, ok = phoneBook.Get("0222222222")
+			require.True(t, ok)
+			require.Equal(t, other, got)
+			require.Equal(t, sequence, phoneBook.Sequence())
+		})
+	}
+}
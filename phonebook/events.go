@@ -0,0 +1,247 @@
+package phonebook
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType is the type of change to a contact.
+type EventType string
+
+const (
+	Added   EventType = "added"
+	Updated EventType = "updated"
+	Deleted EventType = "deleted"
+)
+
+// defaultRetention is the default number of events retained for subscribers to
+// resume from.
+const defaultRetention = 1024
+
+// defaultBuffer is the default number of events buffered for a subscriber.
+const defaultBuffer = 64
+
+// ErrLagged is returned by Subscription.Err when a subscription is closed
+// because the subscriber did not receive its events fast enough. The subscriber
+// can resume from the sequence of the last event it received.
+var ErrLagged = errors.New("subscriber lagged behind the change feed")
+
+// Event describes a change to a contact.
+type Event struct {
+	// Sequence is the position of the event in the change feed, which starts at
+	// one and increases by one for each change.
+	Sequence uint64
+	Type     EventType
+	Time     time.Time
+	// Before is the contact before the change, which is nil for added contacts.
+	Before *Contact
+	// After is the contact after the change, which is nil for deleted contacts.
+	After *Contact
+}
+
+// Filter specifies the events received by a subscription.
+type Filter struct {
+	// Types are the types of events to receive. Every type is received if empty.
+	Types []EventType
+	// Match returns whether to receive an event for a contact. An event is
+	// received if either its before or after contact matches. Every event is
+	// received if nil. Match is called while the change is being made, with the
+	// phone book locked, so it must be fast and must not call any of the phone
+	// book's methods, which would deadlock.
+	Match func(Contact) bool
+	// After resumes the feed from the event following the specified sequence,
+	// replaying retained events the subscriber missed. The feed starts from the
+	// next change if zero.
+	After uint64
+	// Buffer is the number of events buffered for the subscriber before it is
+	// considered lagged. Defaults to 64.
+	Buffer int
+}
+
+// Subscription receives the events of a phone book's change feed.
+type Subscription struct {
+	feed   *feed
+	filter Filter
+	events chan Event
+	err    error
+}
+
+// Events returns the channel the subscription's events are sent on, which is
+// closed when the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns ErrLagged if the subscription was closed because the subscriber
+// fell behind, or nil otherwise.
+func (s *Subscription) Err() error {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.err
+}
+
+// Close stops the subscription and closes its events channel.
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	s.feed.unsubscribe(s, nil)
+}
+
+// Subscribe returns a subscription to the events of contacts that are added,
+// updated or deleted. Events are never blocked by a slow subscriber. Instead, a
+// subscriber that fills its buffer is closed with ErrLagged, and can resubscribe
+// from the last sequence it received. An error is returned if the events after
+// the specified sequence are no longer retained.
+func (p *PhoneBook) Subscribe(filter Filter) (*Subscription, error) {
+	return p.feed.subscribe(filter)
+}
+
+// Sequence returns the sequence of the latest event in the change feed, which
+// can be used to subscribe to the changes following a read.
+func (p *PhoneBook) Sequence() uint64 {
+	p.feed.mu.Lock()
+	defer p.feed.mu.Unlock()
+	return p.feed.sequence
+}
+
+// feed holds the subscriptions to a phone book's changes, and retains the most
+// recent events so that subscribers can resume.
+type feed struct {
+	mu        sync.Mutex
+	sequence  uint64
+	retention int
+	// retained holds the most recent events in sequence order.
+	retained    []Event
+	subscribers map[*Subscription]bool
+}
+
+func newFeed(retention int) *feed {
+	return &feed{
+		retention:   retention,
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+func (f *feed) subscribe(filter Filter) (*Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if filter.Buffer <= 0 {
+		filter.Buffer = defaultBuffer
+	}
+
+	var replay []Event
+	if filter.After > 0 && filter.After < f.sequence {
+		if len(f.retained) == 0 || f.retained[0].Sequence > filter.After+1 {
+			return nil, fmt.Errorf("events after sequence %d are no longer retained", filter.After)
+		}
+		for _, event := range f.retained {
+			if event.Sequence > filter.After && filter.matches(event) {
+				replay = append(replay, event)
+			}
+		}
+	} else if filter.After > f.sequence {
+		return nil, fmt.Errorf("sequence %d has not been reached", filter.After)
+	}
+
+	sub := &Subscription{
+		feed:   f,
+		filter: filter,
+		events: make(chan Event, filter.Buffer+len(replay)),
+	}
+	for _, event := range replay {
+		sub.events <- event
+	}
+	f.subscribers[sub] = true
+	return sub, nil
+}
+
+// publish records a change and sends it to every matching subscriber. Pass a nil
+// before contact for an added contact, or a nil after contact for a deleted
+// contact.
+func (f *feed) publish(before *Contact, after *Contact) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sequence++
+	event := Event{Sequence: f.sequence, Time: time.Now()}
+	switch {
+	case before == nil:
+		event.Type = Added
+	case after == nil:
+		event.Type = Deleted
+	default:
+		event.Type = Updated
+	}
+	if before != nil {
+		cloned := before.clone()
+		event.Before = &cloned
+	}
+	if after != nil {
+		cloned := after.clone()
+		event.After = &cloned
+	}
+
+	f.retained = append(f.retained, event)
+	if len(f.retained) > f.retention {
+		f.retained = f.retained[len(f.retained)-f.retention:]
+	}
+
+	for sub := range f.subscribers {
+		if !sub.filter.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			f.unsubscribe(sub, ErrLagged)
+		}
+	}
+}
+
+// redact removes the data of the contact with the specified ID from the retained
+// events, leaving only its ID, so that subscribers that resume still learn of
+// its changes.
+func (f *feed) redact(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, event := range f.retained {
+		if event.Before != nil && event.Before.ID == id {
+			f.retained[i].Before = &Contact{ID: id}
+		}
+		if event.After != nil && event.After.ID == id {
+			f.retained[i].After = &Contact{ID: id}
+		}
+	}
+}
+
+// unsubscribe closes the subscription with the specified error. The feed must be
+// locked.
+func (f *feed) unsubscribe(sub *Subscription, err error) {
+	if !f.subscribers[sub] {
+		return
+	}
+	delete(f.subscribers, sub)
+	sub.err = err
+	close(sub.events)
+}
+
+func (f Filter) matches(event Event) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			found = found || t == event.Type
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Match == nil {
+		return true
+	}
+	return (event.Before != nil && f.Match(event.Before.clone())) ||
+		(event.After != nil && f.Match(event.After.clone()))
+}
@@ -0,0 +1,137 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_Subscribe(t *testing.T) {
+	phoneBook := New()
+	sub, err := phoneBook.Subscribe(Filter{})
+	require.NoError(t, err)
+
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+	updated := contact
+	updated.LastName = "Updated"
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), contact.ID, updated))
+	updated.Version = 2
+	require.NoError(t, phoneBook.DeleteByID(context.Background(), contact.ID))
+	require.Equal(t, uint64(3), phoneBook.Sequence())
+
+	event := <-sub.Events()
+	require.Equal(t, uint64(1), event.Sequence)
+	require.Equal(t, Added, event.Type)
+	require.Nil(t, event.Before)
+	require.Equal(t, &contact, event.After)
+	require.False(t, event.Time.IsZero())
+
+	event = <-sub.Events()
+	require.Equal(t, uint64(2), event.Sequence)
+	require.Equal(t, Updated, event.Type)
+	require.Equal(t, &contact, event.Before)
+	require.Equal(t, &updated, event.After)
+
+	event = <-sub.Events()
+	require.Equal(t, uint64(3), event.Sequence)
+	require.Equal(t, Deleted, event.Type)
+	require.Equal(t, &updated, event.Before)
+	require.Nil(t, event.After)
+
+	sub.Close()
+	_, ok := <-sub.Events()
+	require.False(t, ok)
+	require.NoError(t, sub.Err())
+	// Closing twice is a no-op
+	sub.Close()
+}
+
+func TestPhoneBook_Subscribe_filter(t *testing.T) {
+	phoneBook := New()
+	sub, err := phoneBook.Subscribe(Filter{
+		Types: []EventType{Added, Updated},
+		Match: func(contact Contact) bool { return contact.HasTag("vip") },
+	})
+	require.NoError(t, err)
+
+	vip := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"vip"}}
+	other := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &vip)
+	add(t, phoneBook, &other)
+
+	// Events are received while either side of the change matches
+	updated := vip
+	updated.Tags = nil
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), vip.ID, updated))
+	updated.Version = 2
+	require.NoError(t, phoneBook.DeleteByID(context.Background(), updated.ID))
+	sub.Close()
+
+	var got []Event
+	for event := range sub.Events() {
+		got = append(got, event)
+	}
+	require.Len(t, got, 2)
+	require.Equal(t, Added, got[0].Type)
+	require.Equal(t, &vip, got[0].After)
+	require.Equal(t, Updated, got[1].Type)
+	require.Equal(t, &updated, got[1].After)
+}
+
+func TestPhoneBook_Subscribe_lagged(t *testing.T) {
+	phoneBook := New()
+	sub, err := phoneBook.Subscribe(Filter{Buffer: 1})
+	require.NoError(t, err)
+
+	first := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	second := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &first)
+	// A full buffer closes the subscription rather than blocking the change
+	add(t, phoneBook, &second)
+
+	event, ok := <-sub.Events()
+	require.True(t, ok)
+	require.Equal(t, uint64(1), event.Sequence)
+	_, ok = <-sub.Events()
+	require.False(t, ok)
+	require.ErrorIs(t, sub.Err(), ErrLagged)
+
+	// The subscriber resumes from the last sequence it received
+	sub, err = phoneBook.Subscribe(Filter{After: event.Sequence})
+	require.NoError(t, err)
+	event = <-sub.Events()
+	require.Equal(t, uint64(2), event.Sequence)
+	require.Equal(t, &second, event.After)
+	sub.Close()
+}
+
+func TestPhoneBook_Subscribe_resume(t *testing.T) {
+	phoneBook := New(WithEventRetention(2))
+	for _, number := range []string{"0123456789", "9876543210", "5432167890"} {
+		_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile(number), FirstName: "Foo", LastName: "Bar"})
+		require.NoError(t, err)
+	}
+
+	sub, err := phoneBook.Subscribe(Filter{After: 1})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), (<-sub.Events()).Sequence)
+	require.Equal(t, uint64(3), (<-sub.Events()).Sequence)
+	sub.Close()
+
+	// Subscribing from the latest sequence only receives new events
+	sub, err = phoneBook.Subscribe(Filter{After: 3})
+	require.NoError(t, err)
+	require.Empty(t, sub.Events())
+	sub.Close()
+
+	_, err = phoneBook.Subscribe(Filter{After: 4})
+	require.EqualError(t, err, "sequence 4 has not been reached")
+
+	// Events older than the retention can not be resumed from
+	_, err = phoneBook.Add(context.Background(), Contact{Numbers: mobile("1234567890"), FirstName: "Foo", LastName: "Bar"})
+	require.NoError(t, err)
+	_, err = phoneBook.Subscribe(Filter{After: 1})
+	require.EqualError(t, err, "events after sequence 1 are no longer retained")
+}
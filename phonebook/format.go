@@ -0,0 +1,48 @@
+package phonebook
+
+import "strings"
+
+// NumberFormat is a format used to display phone numbers.
+type NumberFormat int
+
+const (
+	// E164 formats numbers in the canonical E.164 format (e.g. +61410000000).
+	E164 NumberFormat = iota
+	// National formats numbers as they are dialled within their region (e.g.
+	// 0410 000 000).
+	National
+	// International formats numbers as they are dialled from outside their
+	// region (e.g. +61 410 000 000).
+	International
+)
+
+// FormatNumber formats a number stored in E.164 format for display, using the
+// format rules of the region of its country calling code. Numbers that are not
+// in E.164 format, such as those stored by TenDigitPolicy, are returned as is.
+func FormatNumber(number string, format NumberFormat) string {
+	if !strings.HasPrefix(number, "+") || format == E164 {
+		return number
+	}
+
+	r, national, ok := splitCallingCode(number[1:], "")
+	if !ok {
+		return number
+	}
+
+	if formatted, ok := r.format(national, format == International); ok {
+		if format == International {
+			return "+" + r.callingCode + " " + formatted
+		}
+		return formatted
+	}
+
+	if format == International {
+		return "+" + r.callingCode + " " + national
+	}
+	return r.trunkPrefix + national
+}
+
+// Format formats the phone number for display. See FormatNumber.
+func (n PhoneNumber) Format(format NumberFormat) string {
+	return FormatNumber(n.Number, format)
+}
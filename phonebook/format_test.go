@@ -0,0 +1,94 @@
+package phonebook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		number string
+		format NumberFormat
+		want   string
+	}{
+		{
+			name:   "AU mobile national",
+			number: "+61410000000",
+			format: National,
+			want:   "0410 000 000",
+		},
+		{
+			name:   "AU mobile international",
+			number: "+61410000000",
+			format: International,
+			want:   "+61 410 000 000",
+		},
+		{
+			name:   "AU landline national",
+			number: "+61212345678",
+			format: National,
+			want:   "(02) 1234 5678",
+		},
+		{
+			name:   "NANP national",
+			number: "+15551234567",
+			format: National,
+			want:   "(555) 123-4567",
+		},
+		{
+			name:   "NANP international",
+			number: "+15551234567",
+			format: International,
+			want:   "+1 555-123-4567",
+		},
+		{
+			name:   "GB London national",
+			number: "+442012345678",
+			format: National,
+			want:   "020 1234 5678",
+		},
+		{
+			name:   "E164",
+			number: "+61410000000",
+			format: E164,
+			want:   "+61410000000",
+		},
+		{
+			name:   "region without format rules national",
+			number: "+81312345678",
+			format: National,
+			want:   "0312345678",
+		},
+		{
+			name:   "region without format rules international",
+			number: "+81312345678",
+			format: International,
+			want:   "+81 312345678",
+		},
+		{
+			name:   "not E164",
+			number: "0123456789",
+			format: International,
+			want:   "0123456789",
+		},
+		{
+			name:   "unknown calling code",
+			number: "+99912345678",
+			format: National,
+			want:   "+99912345678",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, FormatNumber(tt.number, tt.format))
+		})
+	}
+}
+
+func TestPhoneNumber_Format(t *testing.T) {
+	number := PhoneNumber{Type: Mobile, Number: "+61410000000"}
+	require.Equal(t, "0410 000 000", number.Format(National))
+}
@@ -0,0 +1,235 @@
+package phonebook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Revision records a change to a contact.
+type Revision struct {
+	// Number is the position of the revision in the contact's history, which
+	// starts at one when the contact is added.
+	Number int
+	Time   time.Time
+	// Actor identifies who made the change, if known.
+	Actor string
+	Type  EventType
+	// Changes are the fields that changed.
+	Changes []FieldChange
+	// Contact is the contact after the change, or the deleted contact for a
+	// deletion.
+	Contact Contact
+}
+
+// FieldChange describes the change to a field of a contact. Fields holding
+// multiple values are described as a list of their values separated by
+// semicolons, and custom fields are named custom.<name>.
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// History returns the revisions of the contact that owns the specified number,
+// oldest first.
+func (p *PhoneBook) History(number string) ([]Revision, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	contact, ok := p.contactByNumber(number)
+	if !ok {
+		return nil, fmt.Errorf("contact not found for number %s", number)
+	}
+	return p.revisions(contact.ID), nil
+}
+
+// HistoryByID returns the revisions of the contact with the specified ID, oldest
+// first.
+func (p *PhoneBook) HistoryByID(id string) ([]Revision, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if _, ok := p.contacts[id]; !ok {
+		return nil, fmt.Errorf("contact not found for ID %s", id)
+	}
+	return p.revisions(id), nil
+}
+
+// AsOf returns a new phone book holding the contacts as they were at the
+// specified time, along with their history up to that time. The phone book is
+// configured with the options of this phone book, but not with indexes added
+// after it was created. Contacts that were deleted without the trash, or purged
+// from it, have no history, so they are not included even if they existed at
+// the time.
+func (p *PhoneBook) AsOf(t time.Time) *PhoneBook {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	asOf := New(p.opts...)
+	// Rebuilding the phone book is not a change to audit
+	asOf.auditSink = nil
+	for _, id := range p.historyIDs() {
+		var revisions []Revision
+		for _, revision := range p.history[id] {
+			if revision.Time.After(t) {
+				break
+			}
+			revisions = append(revisions, revision)
+		}
+		if len(revisions) == 0 || revisions[len(revisions)-1].Type == Deleted {
+			continue
+		}
+
+		contact := revisions[len(revisions)-1].Contact.clone()
+		_ = asOf.insert(context.Background(), &contact)
+		asOf.history[contact.ID] = revisions
+	}
+	return asOf
+}
+
+// Revert updates the contact that owns the specified number to its state at the
+// specified revision, which is recorded as a new revision. The contact must
+// still be valid for the phone book.
+func (p *PhoneBook) Revert(ctx context.Context, number string, revision int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, ok := p.contactByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found for number %s", number)
+	}
+
+	revisions := p.history[existing.ID]
+	if revision < 1 || revision > len(revisions) {
+		return fmt.Errorf("revision %d not found for number %s", revision, number)
+	}
+
+	return p.update(ctx, existing, revisions[revision-1].Contact.clone())
+}
+
+// record appends a revision for the change to the contact's history. Pass a nil
+// before contact for an added contact, or a nil after contact for a deleted
+// contact.
+func (p *PhoneBook) record(ctx context.Context, before *Contact, after *Contact) {
+	revision := Revision{Time: time.Now(), Type: Updated, Changes: diff(before, after)}
+	revision.Actor, _ = ActorFromContext(ctx)
+	switch {
+	case before == nil:
+		revision.Type = Added
+		revision.Contact = after.clone()
+	case after == nil:
+		revision.Type = Deleted
+		revision.Contact = before.clone()
+	default:
+		revision.Contact = after.clone()
+	}
+	id := revision.Contact.ID
+	revision.Number = len(p.history[id]) + 1
+	p.history[id] = append(p.history[id], revision)
+}
+
+func (p *PhoneBook) revisions(id string) []Revision {
+	revisions := make([]Revision, len(p.history[id]))
+	for i, revision := range p.history[id] {
+		revision.Changes = append([]FieldChange(nil), revision.Changes...)
+		revision.Contact = revision.Contact.clone()
+		revisions[i] = revision
+	}
+	return revisions
+}
+
+// historyIDs returns the IDs of the contacts with history in sorted order. IDs
+// are ULIDs, so this is the order the contacts were added in to the millisecond.
+func (p *PhoneBook) historyIDs() []string {
+	ids := make([]string, 0, len(p.history))
+	for id := range p.history {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// diff returns the fields that differ between the contacts. Pass a nil before
+// contact to describe every field of a new contact, or a nil after contact to
+// describe every field of a deleted contact.
+func diff(before *Contact, after *Contact) []FieldChange {
+	if before == nil {
+		before = &Contact{}
+	}
+	if after == nil {
+		after = &Contact{}
+	}
+
+	var changes []FieldChange
+	compare := func(field string, old string, new string) {
+		if old != new {
+			changes = append(changes, FieldChange{Field: field, Before: old, After: new})
+		}
+	}
+
+	compare("numbers", describeNumbers(before.Numbers), describeNumbers(after.Numbers))
+	compare("first_name", before.FirstName, after.FirstName)
+	compare("last_name", before.LastName, after.LastName)
+	compare("address", before.Address, after.Address)
+	compare("emails", describeEmails(before.Emails), describeEmails(after.Emails))
+	compare("websites", describeWebsites(before.Websites), describeWebsites(after.Websites))
+	compare("messaging", describeMessaging(before.Messaging), describeMessaging(after.Messaging))
+	compare("tags", strings.Join(before.Tags, "; "), strings.Join(after.Tags, "; "))
+
+	names := map[string]bool{}
+	for name := range before.Custom {
+		names[name] = true
+	}
+	for name := range after.Custom {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		compare("custom."+name, before.Custom[name], after.Custom[name])
+	}
+
+	return changes
+}
+
+func describeNumbers(numbers []PhoneNumber) string {
+	described := make([]string, len(numbers))
+	for i, number := range numbers {
+		if number.Label != "" {
+			described[i] = fmt.Sprintf("%s (%s) %s", number.Type, number.Label, number.Number)
+		} else {
+			described[i] = fmt.Sprintf("%s %s", number.Type, number.Number)
+		}
+	}
+	return strings.Join(described, "; ")
+}
+
+func describeEmails(emails []Email) string {
+	described := make([]string, len(emails))
+	for i, email := range emails {
+		described[i] = fmt.Sprintf("%s %s", email.Type, email.Address)
+	}
+	return strings.Join(described, "; ")
+}
+
+func describeWebsites(websites []Website) string {
+	described := make([]string, len(websites))
+	for i, website := range websites {
+		described[i] = fmt.Sprintf("%s %s", website.Type, website.URL)
+	}
+	return strings.Join(described, "; ")
+}
+
+func describeMessaging(handles []MessagingHandle) string {
+	described := make([]string, len(handles))
+	for i, handle := range handles {
+		described[i] = fmt.Sprintf("%s %s", handle.Service, handle.Handle)
+	}
+	return strings.Join(described, "; ")
+}
@@ -0,0 +1,135 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_History(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	add(t, phoneBook, &contact)
+
+	updated := contact
+	updated.Address = newAddress("Bar City")
+	updated.Custom = map[string]string{"employee_id": "123"}
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), contact.ID, updated))
+	updated.Version = 2
+
+	got, err := phoneBook.History("0123456789")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	require.Equal(t, 1, got[0].Number)
+	require.Equal(t, Added, got[0].Type)
+	require.Equal(t, contact, got[0].Contact)
+	require.Equal(t, []FieldChange{
+		{Field: "numbers", After: "mobile 0123456789"},
+		{Field: "first_name", After: "One"},
+		{Field: "last_name", After: "One"},
+		{Field: "address", After: newAddress("Foo City")},
+	}, got[0].Changes)
+
+	require.Equal(t, 2, got[1].Number)
+	require.Equal(t, Updated, got[1].Type)
+	require.Equal(t, updated, got[1].Contact)
+	require.Equal(t, []FieldChange{
+		{Field: "address", Before: newAddress("Foo City"), After: newAddress("Bar City")},
+		{Field: "custom.employee_id", After: "123"},
+	}, got[1].Changes)
+	require.False(t, got[1].Time.Before(got[0].Time))
+
+	byID, err := phoneBook.HistoryByID(contact.ID)
+	require.NoError(t, err)
+	require.Equal(t, got, byID)
+
+	// Returned revisions can not modify the stored history
+	got[0].Contact.Numbers[0].Number = "9999999999"
+	got, err = phoneBook.History("0123456789")
+	require.NoError(t, err)
+	require.Equal(t, contact, got[0].Contact)
+
+	// History is deleted with the contact
+	require.NoError(t, phoneBook.Delete(context.Background(), "0123456789"))
+	require.Empty(t, phoneBook.history)
+	_, err = phoneBook.History("0123456789")
+	require.EqualError(t, err, "contact not found for number 0123456789")
+	_, err = phoneBook.HistoryByID(contact.ID)
+	require.EqualError(t, err, "contact not found for ID "+contact.ID)
+}
+
+func TestPhoneBook_AsOf(t *testing.T) {
+	phoneBook := New(WithUniqueEmails())
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	add(t, phoneBook, &contact)
+	before := time.Now()
+	time.Sleep(time.Millisecond)
+
+	updated := contact
+	updated.Address = newAddress("Bar City")
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), contact.ID, updated))
+	updated.Version = 2
+	later := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &later)
+
+	asOf := phoneBook.AsOf(before)
+	got, ok := asOf.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, contact, got)
+	require.Equal(t, []Contact{contact}, asOf.FindByCity("Foo City"))
+	_, ok = asOf.Get("9876543210")
+	require.False(t, ok)
+	history, err := asOf.History("0123456789")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.True(t, asOf.uniqueEmails)
+
+	asOf = phoneBook.AsOf(time.Now())
+	got, ok = asOf.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, updated, got)
+	_, ok = asOf.Get("9876543210")
+	require.True(t, ok)
+
+	require.Empty(t, phoneBook.AsOf(before.Add(-time.Hour)).contacts)
+}
+
+func TestPhoneBook_Revert(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	add(t, phoneBook, &contact)
+
+	updated := contact
+	updated.Address = newAddress("Bar City")
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), contact.ID, updated))
+
+	require.NoError(t, phoneBook.Revert(context.Background(), "0123456789", 1))
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	// Reverting is a change, so the version is not reverted
+	reverted := contact
+	reverted.Version = 3
+	require.Equal(t, reverted, got)
+
+	history, err := phoneBook.History("0123456789")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, []FieldChange{
+		{Field: "address", Before: newAddress("Bar City"), After: newAddress("Foo City")},
+	}, history[2].Changes)
+
+	require.EqualError(t, phoneBook.Revert(context.Background(), "0123456789", 4), "revision 4 not found for number 0123456789")
+	require.EqualError(t, phoneBook.Revert(context.Background(), "0123456789", 0), "revision 0 not found for number 0123456789")
+	require.EqualError(t, phoneBook.Revert(context.Background(), "9876543210", 1), "contact not found for number 9876543210")
+
+	// Reverting to a number that now belongs to another contact fails
+	renumbered := contact
+	renumbered.Numbers = mobile("1111111111")
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), contact.ID, renumbered))
+	other := Contact{Numbers: mobile("0123456789"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &other)
+	require.EqualError(t, phoneBook.Revert(context.Background(), "1111111111", 1), "contact already exists for new number 0123456789")
+}
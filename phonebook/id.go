@@ -0,0 +1,40 @@
+package phonebook
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet used to encode IDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newID returns a new ULID, which is a 128 bit identifier made up of a 48 bit
+// millisecond timestamp followed by 80 random bits. IDs are encoded as 26
+// Crockford base32 characters, so they sort lexicographically by creation time.
+func newID(now time.Time) string {
+	var id [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(now.UnixMilli()))
+	copy(id[:6], ts[2:])
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(err)
+	}
+
+	// 26 characters hold 130 bits, so the first character only encodes the top 3
+	// bits of the ID.
+	encoded := make([]byte, 26)
+	for i := range encoded {
+		bit := i*5 - 2
+		var v byte
+		for j := 0; j < 5; j++ {
+			v <<= 1
+			if b := bit + j; b >= 0 && id[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 1
+			}
+		}
+		encoded[i] = crockford[v]
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,23 @@
+package phonebook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewID(t *testing.T) {
+	now := time.Now()
+	id := newID(now)
+	require.Len(t, id, 26)
+	require.Regexp(t, "^[0-7][0-9A-HJKMNP-TV-Z]{25}$", id)
+	require.NotEqual(t, id, newID(now))
+}
+
+func TestNewID_sortsByTime(t *testing.T) {
+	earlier := newID(time.UnixMilli(1000))
+	later := newID(time.UnixMilli(2000))
+	require.Less(t, earlier, later)
+	require.Equal(t, "00000000Z8", earlier[:10])
+}
@@ -0,0 +1,111 @@
+package phonebook
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joshjon/go-phonebook/internal/index"
+)
+
+// ConstraintError is returned when a contact has a key that already belongs to
+// another contact in a unique index.
+type ConstraintError struct {
+	// Constraint is the name of the unique index that was violated.
+	Constraint string
+	Key        string
+	// ContactID is the ID of the contact that the key already belongs to.
+	ContactID string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("unique constraint %s violated: %s already exists", e.Constraint, e.Key)
+}
+
+// AddIndex registers a named index of contacts, which can be queried with
+// FindByIndex. The key function returns the key a contact is indexed under, or
+// false to skip the contact. The index is built from the contacts already in the
+// phone book.
+func (p *PhoneBook) AddIndex(name string, keyFn func(Contact) (string, bool)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("index name required")
+	}
+	return p.register(newMapIndex(name, keyFn))
+}
+
+// AddUniqueIndex registers a named unique index of contacts. Adding or updating
+// a contact with a key that already belongs to another contact fails with a
+// ConstraintError. The index is built from the contacts already in the phone
+// book, and is not added if any of them share a key.
+func (p *PhoneBook) AddUniqueIndex(name string, keyFn func(Contact) (string, bool)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("index name required")
+	}
+	return p.register(newUniqueIndex(name, keyFn))
+}
+
+// FindByIndex returns all contacts with the specified key in the named index.
+func (p *PhoneBook) FindByIndex(name string, key string) ([]Contact, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !p.indexes.Has(name) {
+		return nil, fmt.Errorf("index %s not found", name)
+	}
+	if found, ok := p.indexes.Get(name, key); ok {
+		return clones(found), nil
+	}
+	return []Contact{}, nil
+}
+
+// register adds the index to the phone book and builds it from the existing
+// contacts.
+func (p *PhoneBook) register(idx index.Index[*Contact]) error {
+	existing := make([]*Contact, 0, len(p.contacts))
+	for _, contact := range p.contacts {
+		existing = append(existing, contact)
+	}
+	return constraintError(p.indexes.Register(idx, existing))
+}
+
+// checkConstraints returns a ConstraintError if the existing contact can not be
+// replaced by the new contact in every unique index. Pass a nil existing contact
+// to check a new contact.
+func (p *PhoneBook) checkConstraints(existing *Contact, contact *Contact) error {
+	return constraintError(p.indexes.Check(existing, contact))
+}
+
+// constraintError converts a conflict error from a unique index to a
+// ConstraintError.
+func constraintError(err error) error {
+	var conflictErr *index.ConflictError[*Contact]
+	if errors.As(err, &conflictErr) {
+		return &ConstraintError{
+			Constraint: conflictErr.Index,
+			Key:        conflictErr.Key,
+			ContactID:  conflictErr.Existing.ID,
+		}
+	}
+	return err
+}
+
+func newMapIndex(name string, keyFn func(Contact) (string, bool)) *index.MapIndex[*Contact] {
+	return index.NewMapIndex(name, func(contact *Contact) (string, bool) {
+		// Pass a copy so the key function can not modify the stored contact
+		return keyFn(contact.clone())
+	})
+}
+
+func newUniqueIndex(name string, keyFn func(Contact) (string, bool)) *index.UniqueIndex[*Contact] {
+	return index.NewUniqueIndex(name, func(contact *Contact) []string {
+		if key, ok := keyFn(contact.clone()); ok {
+			return []string{key}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,137 @@
+package phonebook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_FindByIndex(t *testing.T) {
+	phoneBook := New(WithIndex("state", stateFromAddress))
+	state := "Foo State"
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	want2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: newAddress("Bar City")}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three"}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
+
+	got, err := phoneBook.FindByIndex("state", state)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Contact{want1, want2}, got)
+
+	got, err = phoneBook.FindByIndex("state", "random")
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	require.NoError(t, phoneBook.Delete(context.Background(), want1.Numbers[0].Number))
+	got, err = phoneBook.FindByIndex("state", state)
+	require.NoError(t, err)
+	require.Equal(t, []Contact{want2}, got)
+
+	_, err = phoneBook.FindByIndex("random", state)
+	require.EqualError(t, err, "index random not found")
+}
+
+func TestPhoneBook_AddIndex(t *testing.T) {
+	phoneBook := New()
+	want := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	dummy := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &want)
+	add(t, phoneBook, &dummy)
+
+	// Indexes added to a populated phone book are built from existing contacts
+	require.NoError(t, phoneBook.AddIndex("state", stateFromAddress))
+	got, err := phoneBook.FindByIndex("state", "Foo State")
+	require.NoError(t, err)
+	require.Equal(t, []Contact{want}, got)
+
+	// Updates are reflected in the index
+	updated := want
+	updated.Address = "1 Foo St, Foo City, Bar State, 1111, Foo Country"
+	require.NoError(t, phoneBook.Update(context.Background(), want.Numbers[0].Number, updated))
+	updated.Version = 2
+	got, err = phoneBook.FindByIndex("state", "Foo State")
+	require.NoError(t, err)
+	require.Empty(t, got)
+	got, err = phoneBook.FindByIndex("state", "Bar State")
+	require.NoError(t, err)
+	require.Equal(t, []Contact{updated}, got)
+
+	require.EqualError(t, phoneBook.AddIndex("state", stateFromAddress), "index state already exists")
+	require.EqualError(t, phoneBook.AddIndex(indexCity, stateFromAddress), "index city already exists")
+	require.EqualError(t, phoneBook.AddIndex("", stateFromAddress), "index name required")
+}
+
+func TestWithIndex_duplicatePanics(t *testing.T) {
+	require.PanicsWithError(t, "index state already exists", func() {
+		New(WithIndex("state", stateFromAddress), WithIndex("state", stateFromAddress))
+	})
+}
+
+func TestPhoneBook_uniqueIndex(t *testing.T) {
+	phoneBook := New(WithUniqueIndex("full_address", addressKey))
+	existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	other := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: newAddress("Bar City")}
+	add(t, phoneBook, &existing)
+	add(t, phoneBook, &other)
+
+	// A failed add leaves no partial state
+	conflicting := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Address: existing.Address}
+	_, err := phoneBook.Add(context.Background(), conflicting)
+	var constraintErr *ConstraintError
+	require.True(t, errors.As(err, &constraintErr))
+	require.Equal(t, &ConstraintError{Constraint: "full_address", Key: existing.Address, ContactID: existing.ID}, constraintErr)
+	_, ok := phoneBook.Get(conflicting.Numbers[0].Number)
+	require.False(t, ok)
+	require.Empty(t, phoneBook.FindByName("Three", ""))
+
+	// A failed update leaves the existing contact intact
+	updated := other
+	updated.FirstName = "Updated"
+	updated.Address = existing.Address
+	err = phoneBook.Update(context.Background(), other.Numbers[0].Number, updated)
+	require.True(t, errors.As(err, &constraintErr))
+	got, ok := phoneBook.Get(other.Numbers[0].Number)
+	require.True(t, ok)
+	require.Equal(t, other, got)
+
+	found, err := phoneBook.FindByIndex("full_address", existing.Address)
+	require.NoError(t, err)
+	require.Equal(t, []Contact{existing}, found)
+}
+
+func TestPhoneBook_AddUniqueIndex(t *testing.T) {
+	phoneBook := New()
+	contact1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	contact2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: newAddress("Foo City")}
+	add(t, phoneBook, &contact1)
+	add(t, phoneBook, &contact2)
+
+	// Existing contacts that share a key prevent the index from being added
+	err := phoneBook.AddUniqueIndex("full_address", addressKey)
+	var constraintErr *ConstraintError
+	require.True(t, errors.As(err, &constraintErr))
+	require.Equal(t, "full_address", constraintErr.Constraint)
+	_, err = phoneBook.FindByIndex("full_address", contact1.Address)
+	require.EqualError(t, err, "index full_address not found")
+
+	require.NoError(t, phoneBook.Delete(context.Background(), contact2.Numbers[0].Number))
+	require.NoError(t, phoneBook.AddUniqueIndex("full_address", addressKey))
+	_, err = phoneBook.Add(context.Background(), Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Address: contact1.Address})
+	require.True(t, errors.As(err, &constraintErr))
+}
+
+func addressKey(contact Contact) (string, bool) {
+	return contact.Address, contact.Address != ""
+}
+
+func stateFromAddress(contact Contact) (string, bool) {
+	if contact.Address != "" {
+		return strings.Split(contact.Address, ", ")[2], true
+	}
+	return "", false
+}
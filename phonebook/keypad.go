@@ -0,0 +1,92 @@
+package phonebook
+
+import (
+	"strings"
+
+	"github.com/deckarep/golang-set/v2"
+)
+
+// keypadDigits holds the digit of each letter on a phone keypad.
+var keypadDigits = func() map[rune]byte {
+	digits := map[rune]byte{}
+	for i, letters := range []string{"abc", "def", "ghi", "jkl", "mno", "pqrs", "tuv", "wxyz"} {
+		for _, letter := range letters {
+			digits[letter] = byte('2' + i)
+		}
+	}
+	return digits
+}()
+
+// FindByKeypad returns all contacts whose first or last name starts with the
+// letters of the specified keypad digits (e.g. 7648 matches Smith, which is 76484
+// on a phone keypad), as well as all contacts with a number that starts with the
+// digits.
+func (p *PhoneBook) FindByKeypad(digits string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	digits = formatting.ReplaceAllString(digits, "")
+	if digits == "" || !isDigits(digits) {
+		return []Contact{}
+	}
+
+	var ids []string
+	if prefix, ok := p.policy.NormalizePrefix(digits); ok {
+		if found, ok := p.numbers.FindByPrefix(numberKey(prefix)); ok {
+			ids = append(ids, found...)
+		}
+	}
+	if found, ok := p.keypad.FindByPrefix(digits); ok {
+		for _, set := range found {
+			ids = append(ids, set.ToSlice()...)
+		}
+	}
+	return p.contactsByID(ids)
+}
+
+// addKeypadNames adds the keypad encoding of the contact's names to the keypad
+// trie.
+func (p *PhoneBook) addKeypadNames(contact *Contact) {
+	for _, key := range keypadKeys(contact) {
+		if ids, ok := p.keypad.Get(key); ok {
+			ids.Add(contact.ID)
+		} else {
+			_ = p.keypad.Insert(key, mapset.NewSet[string](contact.ID))
+		}
+	}
+}
+
+// removeKeypadNames removes the keypad encoding of the contact's names from the
+// keypad trie.
+func (p *PhoneBook) removeKeypadNames(contact *Contact) {
+	for _, key := range keypadKeys(contact) {
+		if ids, ok := p.keypad.Get(key); ok {
+			ids.Remove(contact.ID)
+			if ids.Cardinality() == 0 {
+				p.keypad.Delete(key)
+			}
+		}
+	}
+}
+
+func keypadKeys(contact *Contact) []string {
+	var keys []string
+	for _, name := range []string{contact.FirstName, contact.LastName} {
+		if key := keypadEncode(name); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keypadEncode returns the keypad digits of the letters in the name, ignoring any
+// other characters (e.g. "O'Brien" is 627436).
+func keypadEncode(name string) string {
+	var encoded strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if digit, ok := keypadDigits[r]; ok {
+			encoded.WriteByte(digit)
+		}
+	}
+	return encoded.String()
+}
@@ -0,0 +1,70 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_FindByKeypad(t *testing.T) {
+	phoneBook := New()
+	smith := Contact{Numbers: mobile("0123456789"), FirstName: "John", LastName: "Smith"}
+	sam := Contact{Numbers: mobile("9876543210"), FirstName: "Sam", LastName: "O'Brien"}
+	byNumber := Contact{Numbers: mobile("7648000000"), FirstName: "Anne", LastName: "Lee"}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Foo", LastName: "Bar"}
+	add(t, phoneBook, &smith)
+	add(t, phoneBook, &sam)
+	add(t, phoneBook, &byNumber)
+	add(t, phoneBook, &dummy)
+
+	tests := []struct {
+		name   string
+		digits string
+		want   []Contact
+	}{
+		{name: "last name prefix", digits: "7648", want: []Contact{smith, byNumber}},
+		{name: "full last name", digits: "76484", want: []Contact{smith}},
+		{name: "first name", digits: "5646", want: []Contact{smith}},
+		{name: "name with punctuation", digits: "627436", want: []Contact{sam}},
+		{name: "shared prefix", digits: "72", want: []Contact{sam}},
+		{name: "number prefix", digits: "0123", want: []Contact{smith}},
+		{name: "formatted digits", digits: "764-8", want: []Contact{smith, byNumber}},
+		{name: "no match", digits: "999", want: []Contact{}},
+		{name: "invalid digits", digits: "76a", want: []Contact{}},
+		{name: "empty", digits: "", want: []Contact{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ElementsMatch(t, tt.want, phoneBook.FindByKeypad(tt.digits))
+		})
+	}
+}
+
+func TestPhoneBook_FindByKeypad_updates(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "John", LastName: "Smith"}
+	other := Contact{Numbers: mobile("9876543210"), FirstName: "Jane", LastName: "Smith"}
+	add(t, phoneBook, &contact)
+	add(t, phoneBook, &other)
+
+	updated := contact
+	updated.LastName = "Jones"
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), contact.ID, updated))
+	updated.Version = 2
+	require.Equal(t, []Contact{other}, phoneBook.FindByKeypad("76484"))
+	require.Equal(t, []Contact{updated}, phoneBook.FindByKeypad("56637"))
+
+	require.NoError(t, phoneBook.DeleteByID(context.Background(), other.ID))
+	require.Empty(t, phoneBook.FindByKeypad("76484"))
+	_, ok := phoneBook.keypad.Get("76484")
+	require.False(t, ok)
+}
+
+func TestKeypadEncode(t *testing.T) {
+	require.Equal(t, "76484", keypadEncode("Smith"))
+	require.Equal(t, "627436", keypadEncode("O'Brien"))
+	require.Equal(t, "22233344455566677778889999", keypadEncode("abcdefghijklmnopqrstuvwxyz"))
+	require.Equal(t, "", keypadEncode("123"))
+}
@@ -0,0 +1,165 @@
+package phonebook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// formatting matches the characters that are commonly used to format phone
+// numbers for readability, which are removed when a number is normalized.
+var formatting = regexp.MustCompile(`[\s\-().]`)
+
+// NumberPolicy validates the phone numbers accepted by a phone book and
+// converts them to the canonical form they are stored and searched in.
+type NumberPolicy interface {
+	// Normalize returns the canonical form of the number, or an error if the
+	// number is not valid.
+	Normalize(number string) (string, error)
+	// NormalizePrefix returns the canonical form of a partial number used for a
+	// prefix search, or false if it can never match a canonical number.
+	NormalizePrefix(prefix string) (string, bool)
+}
+
+// TenDigitPolicy is the default NumberPolicy, which accepts numbers containing
+// exactly 10 digits without a country code.
+type TenDigitPolicy struct{}
+
+// Normalize removes formatting from the number and checks it contains 10 digits.
+func (TenDigitPolicy) Normalize(number string) (string, error) {
+	number = formatting.ReplaceAllString(number, "")
+	if matched, err := regexp.MatchString("^\\d{10}$", number); err != nil || !matched {
+		return "", fmt.Errorf("phone number must contain 10 digits")
+	}
+	return number, nil
+}
+
+// NormalizePrefix removes formatting from the prefix.
+func (TenDigitPolicy) NormalizePrefix(prefix string) (string, bool) {
+	prefix = formatting.ReplaceAllString(prefix, "")
+	if matched, err := regexp.MatchString("^\\d{0,10}$", prefix); err != nil || !matched {
+		return "", false
+	}
+	return prefix, true
+}
+
+// E164Policy is a NumberPolicy that accepts international numbers and stores
+// them in E.164 format (e.g. +61410000000). Numbers starting with a plus sign
+// are parsed in international format, otherwise they are parsed in the national
+// format of the default region. International numbers of regions without
+// metadata are accepted if they have an assigned country calling code and at
+// most 15 digits.
+type E164Policy struct {
+	// DefaultRegion is the ISO 3166-1 alpha-2 code of the region used to parse
+	// numbers in national format. National numbers are rejected if empty.
+	DefaultRegion string
+}
+
+// Normalize parses the number and returns it in E.164 format.
+func (p E164Policy) Normalize(number string) (string, error) {
+	digits := formatting.ReplaceAllString(number, "")
+
+	if strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+		if !isDigits(digits) {
+			return "", fmt.Errorf("phone number %s contains invalid characters", number)
+		}
+		if r, national, ok := splitCallingCode(digits, p.DefaultRegion); ok {
+			if !r.validLength(national) {
+				return "", fmt.Errorf("phone number %s has an invalid length for region %s", number, r.code)
+			}
+			return "+" + digits, nil
+		}
+
+		// Numbers of regions without metadata are only checked against the
+		// limits of E.164
+		code, ok := assignedCallingCode(digits)
+		if !ok {
+			return "", fmt.Errorf("phone number %s has an unknown country calling code", number)
+		} else if len(digits)-len(code) < minNationalDigits || len(digits) > maxE164Digits {
+			return "", fmt.Errorf("phone number %s has an invalid length", number)
+		}
+		return "+" + digits, nil
+	}
+
+	if !isDigits(digits) {
+		return "", fmt.Errorf("phone number %s contains invalid characters", number)
+	}
+
+	r, ok := regions[p.DefaultRegion]
+	if !ok {
+		return "", fmt.Errorf("phone number %s must be in international format", number)
+	}
+
+	// The trunk prefix is removed the same way as in NormalizePrefix, so national
+	// and international input have the same canonical form
+	national := strings.TrimPrefix(digits, r.trunkPrefix)
+	if !r.validLength(national) {
+		return "", fmt.Errorf("phone number %s has an invalid length for region %s", number, r.code)
+	}
+
+	return "+" + r.callingCode + national, nil
+}
+
+// NormalizePrefix converts the prefix to the E.164 format. National prefixes
+// are converted using the default region.
+func (p E164Policy) NormalizePrefix(prefix string) (string, bool) {
+	digits := formatting.ReplaceAllString(prefix, "")
+
+	if strings.HasPrefix(digits, "+") {
+		if !isDigits(digits[1:]) {
+			return "", false
+		}
+		return digits, true
+	}
+
+	r, ok := regions[p.DefaultRegion]
+	if !ok || !isDigits(digits) {
+		return "", false
+	}
+
+	return "+" + r.callingCode + strings.TrimPrefix(digits, r.trunkPrefix), true
+}
+
+// splitCallingCode splits the digits of an international number into the region
+// of its country calling code and its national significant number. When regions
+// share the calling code, the preferred region is used if it is one of them.
+func splitCallingCode(digits string, preferred string) (region, string, bool) {
+	for i := 1; i <= 3 && i < len(digits); i++ {
+		if rs, ok := regionsByCallingCode[digits[:i]]; ok {
+			for _, r := range rs {
+				if r.code == preferred {
+					return r, digits[i:], true
+				}
+			}
+			return rs[0], digits[i:], true
+		}
+	}
+	return region{}, "", false
+}
+
+// assignedCallingCode returns the country calling code of the digits of an
+// international number, if it has been assigned.
+func assignedCallingCode(digits string) (string, bool) {
+	for i := 1; i <= 3 && i < len(digits); i++ {
+		if callingCodes[digits[:i]] {
+			return digits[:i], true
+		}
+	}
+	return "", false
+}
+
+// numberKey returns the key used to store a canonical number in a NumberTrie,
+// which only supports digits.
+func numberKey(canonical string) string {
+	return strings.TrimPrefix(canonical, "+")
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
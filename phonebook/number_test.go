@@ -0,0 +1,205 @@
+package phonebook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenDigitPolicy_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "digits only",
+			number: "0123456789",
+			want:   "0123456789",
+		},
+		{
+			name:   "formatted",
+			number: "(01) 2345-6789",
+			want:   "0123456789",
+		},
+		{
+			name:    "contains invalid chars",
+			number:  "0123K56P89",
+			wantErr: "phone number must contain 10 digits",
+		},
+		{
+			name:    "not length 10",
+			number:  "012345678",
+			wantErr: "phone number must contain 10 digits",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TenDigitPolicy{}.Normalize(tt.number)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestE164Policy_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  string
+		number  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "international",
+			number: "+61410000000",
+			want:   "+61410000000",
+		},
+		{
+			name:   "international formatted",
+			number: "+61 410 000 000",
+			want:   "+61410000000",
+		},
+		{
+			name:   "international three digit calling code",
+			number: "+64 21 123 4567",
+			want:   "+64211234567",
+		},
+		{
+			name:   "national with trunk prefix",
+			region: "AU",
+			number: "0410 000 000",
+			want:   "+61410000000",
+		},
+		{
+			name:   "national without trunk prefix",
+			region: "AU",
+			number: "410000000",
+			want:   "+61410000000",
+		},
+		{
+			name:   "national NANP",
+			region: "US",
+			number: "(555) 123-4567",
+			want:   "+15551234567",
+		},
+		{
+			name:   "national NANP with trunk prefix",
+			region: "US",
+			number: "1-555-123-4567",
+			want:   "+15551234567",
+		},
+		{
+			name:    "national without default region",
+			number:  "0410000000",
+			wantErr: "phone number 0410000000 must be in international format",
+		},
+		{
+			name:   "calling code without region metadata",
+			number: "+86 138 0013 8000",
+			want:   "+8613800138000",
+		},
+		{
+			name:   "one digit calling code without region metadata",
+			number: "+7 912 345 6789",
+			want:   "+79123456789",
+		},
+		{
+			name:    "too short without region metadata",
+			number:  "+55 123",
+			wantErr: "phone number +55 123 has an invalid length",
+		},
+		{
+			name:    "too long without region metadata",
+			number:  "+55 1234 5678 9012 34",
+			wantErr: "phone number +55 1234 5678 9012 34 has an invalid length",
+		},
+		{
+			name:    "unknown calling code",
+			number:  "+999 1234 5678",
+			wantErr: "phone number +999 1234 5678 has an unknown country calling code",
+		},
+		{
+			name:    "invalid length for region",
+			number:  "+61 410 000",
+			wantErr: "phone number +61 410 000 has an invalid length for region AU",
+		},
+		{
+			name:    "invalid length for default region sharing calling code",
+			region:  "US",
+			number:  "+1 555 123 456",
+			wantErr: "phone number +1 555 123 456 has an invalid length for region US",
+		},
+		{
+			name:    "invalid national length",
+			region:  "GB",
+			number:  "0123",
+			wantErr: "phone number 0123 has an invalid length for region GB",
+		},
+		{
+			name:    "invalid chars",
+			region:  "AU",
+			number:  "0410 ABC 000",
+			wantErr: "phone number 0410 ABC 000 contains invalid characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := E164Policy{DefaultRegion: tt.region}.Normalize(tt.number)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestE164Policy_NormalizePrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		region string
+		prefix string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "international",
+			prefix: "+61 4",
+			want:   "+614",
+			wantOK: true,
+		},
+		{
+			name:   "national",
+			region: "AU",
+			prefix: "0410",
+			want:   "+61410",
+			wantOK: true,
+		},
+		{
+			name:   "national without default region",
+			prefix: "0410",
+		},
+		{
+			name:   "invalid chars",
+			region: "AU",
+			prefix: "04A",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := E164Policy{DefaultRegion: tt.region}.NormalizePrefix(tt.prefix)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
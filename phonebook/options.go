@@ -0,0 +1,108 @@
+package phonebook
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option configures a PhoneBook.
+type Option func(*PhoneBook)
+
+// WithNumberPolicy sets the policy used to validate and normalize phone numbers.
+// Defaults to TenDigitPolicy.
+func WithNumberPolicy(policy NumberPolicy) Option {
+	return func(p *PhoneBook) {
+		p.policy = policy
+	}
+}
+
+// WithUniqueEmails requires each email address to belong to at most one contact.
+func WithUniqueEmails() Option {
+	return func(p *PhoneBook) {
+		p.uniqueEmails = true
+	}
+}
+
+// WithPrefixTable sets the table of prefix rules used to classify numbers with
+// Classify. The table is copied, so rules added to it afterwards are not used.
+func WithPrefixTable(table *PrefixTable) Option {
+	return func(p *PhoneBook) {
+		if table != nil {
+			table = table.clone()
+		}
+		p.prefixes = table
+	}
+}
+
+// WithEventRetention sets the number of recent events retained by the change
+// feed, which subscribers can resume from. Defaults to 1024.
+func WithEventRetention(n int) Option {
+	return func(p *PhoneBook) {
+		if n < 0 {
+			n = 0
+		}
+		p.feed.retention = n
+	}
+}
+
+// WithTrash enables the trash, which deleted contacts are moved to instead of
+// being deleted permanently. A contact's numbers stay reserved while it is in
+// the trash. Contacts are purged once they have been in the trash for longer
+// than the retention, or kept until purged if the retention is zero.
+func WithTrash(retention time.Duration) Option {
+	return func(p *PhoneBook) {
+		p.trashEnabled = true
+		p.trashRetention = retention
+	}
+}
+
+// WithAuditSink writes an audit record of each change to the sink. A change is
+// not made if its audit record can not be written. Use ContextWithActor to
+// record who made a change.
+func WithAuditSink(sink AuditSink) Option {
+	return func(p *PhoneBook) {
+		p.auditSink = sink
+	}
+}
+
+// WithCustomField declares a custom field, which specifies whether the field is
+// indexed, unique or required.
+func WithCustomField(field CustomField) Option {
+	return func(p *PhoneBook) {
+		p.customFields[field.Name] = field
+	}
+}
+
+// WithIndex registers a named index of contacts, which can be queried with
+// FindByIndex. The key function returns the key a contact is indexed under, or
+// false to skip the contact. New panics if an index with the same name already
+// exists.
+func WithIndex(name string, keyFn func(Contact) (string, bool)) Option {
+	return func(p *PhoneBook) {
+		p.registered = append(p.registered, newMapIndex(name, keyFn))
+	}
+}
+
+// WithUniqueIndex registers a named unique index of contacts. Adding or updating
+// a contact with a key that already belongs to another contact fails with a
+// ConstraintError. New panics if an index with the same name already exists.
+func WithUniqueIndex(name string, keyFn func(Contact) (string, bool)) Option {
+	return func(p *PhoneBook) {
+		p.registered = append(p.registered, newUniqueIndex(name, keyFn))
+	}
+}
+
+// WithOrderedIndex registers an ordered index of contacts on a named field, which
+// can be queried with FindByRange. The key function returns the value of the
+// field, or false to skip the contact. New panics if the field already has an
+// ordered index.
+func WithOrderedIndex(field string, keyFn func(Contact) (string, bool)) Option {
+	return func(p *PhoneBook) {
+		if _, ok := p.ordered[field]; ok {
+			panic(fmt.Errorf("index %s already exists", orderedIndexName(field)))
+		}
+		idx := newOrderedIndex(field, keyFn)
+		p.ordered[field] = idx
+		p.registered = append(p.registered, idx)
+	}
+}
@@ -0,0 +1,70 @@
+package phonebook
+
+import (
+	"strings"
+)
+
+// FindByNumberPattern returns all contacts with a number matching the specified
+// pattern, where '?' matches any single digit (e.g. "04?0 12? ???"). Patterns
+// may be formatted like a number, and are converted to the canonical form of the
+// phone book's NumberPolicy, so a pattern only matches numbers with the same
+// length. Only the branches of the number trie that can match are searched.
+func (p *PhoneBook) FindByNumberPattern(pattern string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if canonical, ok := p.normalizePattern(pattern); ok {
+		if ids, ok := p.numbers.FindByPattern(numberKey(canonical)); ok {
+			return p.contactsByID(ids)
+		}
+	}
+	return []Contact{}
+}
+
+// FindByNumberSuffix returns all contacts with a number that ends with the
+// specified digits (e.g. 5678).
+func (p *PhoneBook) FindByNumberSuffix(suffix string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	suffix = formatting.ReplaceAllString(suffix, "")
+	if suffix == "" || !isDigits(suffix) {
+		return []Contact{}
+	}
+	if ids, ok := p.suffixes.FindByPrefix(reverse(suffix)); ok {
+		return p.contactsByID(ids)
+	}
+	return []Contact{}
+}
+
+// normalizePattern converts a number pattern to canonical form. The digits
+// before the first wildcard are normalized as a prefix, which adds or removes any
+// leading digits of the canonical form (e.g. a country calling code or trunk
+// prefix), and the rest of the pattern is kept as is.
+func (p *PhoneBook) normalizePattern(pattern string) (string, bool) {
+	pattern = formatting.ReplaceAllString(pattern, "")
+
+	head, rest := pattern, ""
+	if i := strings.IndexByte(pattern, '?'); i >= 0 {
+		head, rest = pattern[:i], pattern[i:]
+	}
+	if !isDigits(strings.ReplaceAll(rest, "?", "")) {
+		return "", false
+	}
+
+	prefix, ok := p.policy.NormalizePrefix(head)
+	if !ok {
+		return "", false
+	}
+	return prefix + rest, true
+}
+
+// reverse returns the digits of a number in reverse order, which is the key
+// used to store a number in the suffix trie.
+func reverse(number string) string {
+	reversed := make([]byte, len(number))
+	for i := 0; i < len(number); i++ {
+		reversed[len(number)-1-i] = number[i]
+	}
+	return string(reversed)
+}
@@ -0,0 +1,76 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_FindByNumberPattern(t *testing.T) {
+	phoneBook := New()
+	first := Contact{Numbers: []PhoneNumber{{Type: Mobile, Number: "0410123456"}, {Type: Work, Number: "0420129999"}}, FirstName: "One", LastName: "One"}
+	second := Contact{Numbers: mobile("0430125678"), FirstName: "Two", LastName: "Two"}
+	dummy := Contact{Numbers: mobile("0510123456"), FirstName: "Three", LastName: "Three"}
+	add(t, phoneBook, &first)
+	add(t, phoneBook, &second)
+	add(t, phoneBook, &dummy)
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []Contact
+	}{
+		{name: "formatted pattern", pattern: "04?0 12? ???", want: []Contact{first, second}},
+		{name: "leading wildcard", pattern: "??10123456", want: []Contact{first, dummy}},
+		{name: "no wildcards", pattern: "0430125678", want: []Contact{second}},
+		{name: "shorter than numbers", pattern: "04?0", want: []Contact{}},
+		{name: "invalid characters", pattern: "04?0 12? ??a", want: []Contact{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ElementsMatch(t, tt.want, phoneBook.FindByNumberPattern(tt.pattern))
+		})
+	}
+}
+
+func TestPhoneBook_FindByNumberPattern_e164(t *testing.T) {
+	phoneBook := New(WithNumberPolicy(E164Policy{DefaultRegion: "AU"}))
+	want := Contact{Numbers: mobile("+61410123456"), FirstName: "One", LastName: "One"}
+	dummy := Contact{Numbers: mobile("+64211234567"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &want)
+	add(t, phoneBook, &dummy)
+
+	require.Equal(t, []Contact{want}, phoneBook.FindByNumberPattern("04?0 12? ???"))
+	require.Equal(t, []Contact{want}, phoneBook.FindByNumberPattern("+61 4?0 ??? 456"))
+	require.Equal(t, []Contact{dummy}, phoneBook.FindByNumberPattern("+64 ?? ??? ????"))
+	require.ElementsMatch(t, []Contact{want, dummy}, phoneBook.FindByNumberPattern("+?? ??? ??? ???"))
+}
+
+func TestPhoneBook_FindByNumberSuffix(t *testing.T) {
+	phoneBook := New()
+	first := Contact{Numbers: []PhoneNumber{{Type: Mobile, Number: "0410125678"}, {Type: Work, Number: "0420005678"}}, FirstName: "One", LastName: "One"}
+	second := Contact{Numbers: mobile("0430125678"), FirstName: "Two", LastName: "Two"}
+	dummy := Contact{Numbers: mobile("0510123456"), FirstName: "Three", LastName: "Three"}
+	add(t, phoneBook, &first)
+	add(t, phoneBook, &second)
+	add(t, phoneBook, &dummy)
+
+	require.ElementsMatch(t, []Contact{first, second}, phoneBook.FindByNumberSuffix("5678"))
+	require.ElementsMatch(t, []Contact{first, second}, phoneBook.FindByNumberSuffix("12 5678"))
+	require.Empty(t, phoneBook.FindByNumberSuffix("9999"))
+	require.Empty(t, phoneBook.FindByNumberSuffix("56a8"))
+	require.Empty(t, phoneBook.FindByNumberSuffix(""))
+
+	// The suffix index is kept in sync with updates and deletes
+	updated := second
+	updated.Numbers = mobile("0430129999")
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), second.ID, updated))
+	updated.Version = 2
+	require.Equal(t, []Contact{first}, phoneBook.FindByNumberSuffix("5678"))
+	require.Equal(t, []Contact{updated}, phoneBook.FindByNumberSuffix("9999"))
+
+	require.NoError(t, phoneBook.DeleteByID(context.Background(), first.ID))
+	require.Empty(t, phoneBook.FindByNumberSuffix("5678"))
+}
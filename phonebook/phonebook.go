@@ -1,76 +1,232 @@
 package phonebook
 
 import (
+	"context"
 	"fmt"
-	"regexp"
 	"strings"
+	"sync"
+	"time"
 
-	mapset "github.com/deckarep/golang-set/v2"
-
+	"github.com/deckarep/golang-set/v2"
 	"github.com/joshjon/go-phonebook/internal/index"
 	"github.com/joshjon/go-phonebook/internal/trie"
 )
 
+// Names of the built-in indexes.
 const (
-	indexFirstName int = iota
-	indexLastName
-	indexFullName
-	indexCity
+	indexFirstName   = "first_name"
+	indexLastName    = "last_name"
+	indexFullName    = "full_name"
+	indexCity        = "city"
+	indexEmail       = "email"
+	indexEmailDomain = "email_domain"
+	indexTag         = "tag"
+	indexCustom      = "custom"
 )
 
-// PhoneBook is a data structure used to master contact information.
+// PhoneBook is a data structure used to master contact information. It is safe
+// for concurrent use.
 type PhoneBook struct {
-	contacts *trie.NumberTrie[Contact]
-	indexes  *index.Indexes[Contact]
+	mu sync.RWMutex
+	// tx is the transaction in progress, if any.
+	tx *Tx
+	// contacts holds each contact by ID, which is the primary key of the phone
+	// book.
+	contacts map[string]*Contact
+	// numbers is a secondary unique index of each phone number to the ID of the
+	// contact that owns it.
+	numbers *trie.NumberTrie[string]
+	// suffixes holds each phone number reversed to the ID of the contact that
+	// owns it, which allows suffix searches.
+	suffixes *trie.NumberTrie[string]
+	// keypad holds the keypad encoding of each first and last name to the IDs
+	// of the contacts with the name.
+	keypad  *trie.NumberTrie[mapset.Set[string]]
+	indexes *index.Indexes[*Contact]
+	tags    *index.MultiMapIndex[*Contact]
+	policy  NumberPolicy
+	// uniqueEmails indicates that an email address may only belong to one
+	// contact, which is enforced by a unique email index.
+	uniqueEmails bool
+	// customFields holds the declared custom fields by name.
+	customFields map[string]CustomField
+	// prefixes holds the rules used to classify numbers, if configured.
+	prefixes *PrefixTable
+	// feed publishes each change to subscribers.
+	feed *feed
+	// trashEnabled indicates that deleted contacts are moved to the trash.
+	trashEnabled bool
+	// trashRetention is how long contacts are kept in the trash before they are
+	// purged. Contacts are kept until purged if zero.
+	trashRetention time.Duration
+	// trash holds the deleted contacts by ID.
+	trash map[string]TrashedContact
+	// reserved holds each number of a contact in the trash to the contact's ID,
+	// so that the number can not be reused until the contact is purged.
+	reserved map[string]string
+	// history holds the revisions of each contact by ID, oldest first.
+	history map[string][]Revision
+	// auditSink stores the audit records of each change, if configured.
+	auditSink AuditSink
+	// auditLast is the last audit record written, which the next record is
+	// chained to.
+	auditLast *AuditRecord
+	// opts are the options the phone book was created with.
+	opts []Option
+	// ordered holds the ordered indexes used for range queries by field.
+	ordered map[string]*index.OrderedIndex[*Contact]
+	// registered holds the indexes registered by options, which are added once
+	// the built-in indexes are created.
+	registered []index.Index[*Contact]
 }
 
-// New returns a new PhoneBook.
-func New() *PhoneBook {
-	return &PhoneBook{
-		contacts: trie.NewNumberTrie[Contact](),
-		indexes: index.NewIndexes[Contact](
-			index.NewMapIndex(indexFirstName, func(contact Contact) (string, bool) { return contact.FirstName, true }),
-			index.NewMapIndex(indexLastName, func(contact Contact) (string, bool) { return contact.LastName, true }),
-			index.NewMapIndex(indexFullName, func(contact Contact) (string, bool) { return contact.FirstName + contact.LastName, true }),
-			index.NewMapIndex(indexCity, func(contact Contact) (string, bool) { return cityFromAddress(contact.Address) }),
-		),
+// New returns a new PhoneBook configured with the provided options.
+func New(opts ...Option) *PhoneBook {
+	p := &PhoneBook{
+		contacts:     map[string]*Contact{},
+		numbers:      trie.NewNumberTrie[string](),
+		suffixes:     trie.NewNumberTrie[string](),
+		keypad:       trie.NewNumberTrie[mapset.Set[string]](),
+		tags:         index.NewMultiMapIndex(indexTag, func(contact *Contact) []string { return contact.Tags }),
+		policy:       TenDigitPolicy{},
+		customFields: map[string]CustomField{},
+		feed:         newFeed(defaultRetention),
+		history:      map[string][]Revision{},
+		trash:        map[string]TrashedContact{},
+		reserved:     map[string]string{},
+		opts:         opts,
+		ordered: map[string]*index.OrderedIndex[*Contact]{
+			rangeFirstName: newOrderedIndex(rangeFirstName, func(contact Contact) (string, bool) { return contact.FirstName, true }),
+			rangeLastName:  newOrderedIndex(rangeLastName, func(contact Contact) (string, bool) { return contact.LastName, true }),
+			rangePostalCode: newOrderedIndex(rangePostalCode, func(contact Contact) (string, bool) {
+				return postalCodeFromAddress(contact.Address)
+			}),
+			rangeNumber: index.NewOrderedIndex(orderedIndexName(rangeNumber), numberKeys),
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	var emailIndex index.Index[*Contact] = index.NewMultiMapIndex(indexEmail, emailKeys)
+	if p.uniqueEmails {
+		emailIndex = index.NewUniqueIndex(indexEmail, emailKeys)
+	}
+
+	p.indexes = index.NewIndexes[*Contact](
+		index.NewMapIndex(indexFirstName, func(contact *Contact) (string, bool) { return contact.FirstName, true }),
+		index.NewMapIndex(indexLastName, func(contact *Contact) (string, bool) { return contact.LastName, true }),
+		index.NewMapIndex(indexFullName, func(contact *Contact) (string, bool) { return contact.FirstName + contact.LastName, true }),
+		index.NewMapIndex(indexCity, func(contact *Contact) (string, bool) { return cityFromAddress(contact.Address) }),
+		emailIndex,
+		index.NewMultiMapIndex(indexEmailDomain, emailDomainKeys),
+		p.tags,
+		index.NewMultiMapIndex(indexCustom, p.customKeys),
+		p.ordered[rangeFirstName],
+		p.ordered[rangeLastName],
+		p.ordered[rangePostalCode],
+		p.ordered[rangeNumber],
+	)
+	for _, name := range p.customFieldNames() {
+		if p.customFields[name].Unique {
+			p.registered = append(p.registered, index.NewUniqueIndex(customIndexName(name), customValueKeys(name)))
+		}
+	}
+
+	for _, idx := range p.registered {
+		if err := p.indexes.Register(idx, nil); err != nil {
+			panic(err)
+		}
 	}
+	p.registered = nil
+
+	return p
 }
 
-// Add adds a contact to the phone book.
-func (p *PhoneBook) Add(contact Contact) error {
-	if err := contact.Validate(); err != nil {
-		return err
+// Add adds a contact to the phone book and returns the ID assigned to it. Each
+// of the contact's numbers is stored in the canonical form of the phone book's
+// NumberPolicy, and must not belong to an existing contact. A ConstraintError is
+// returned if the contact violates a unique index. Nothing is modified if an
+// error is returned.
+func (p *PhoneBook) Add(ctx context.Context, contact Contact) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.add(ctx, contact)
+}
+
+func (p *PhoneBook) add(ctx context.Context, contact Contact) (string, error) {
+	p.expireTrash()
+	contact, _, err := p.stage(nil, contact)
+	if err != nil {
+		return "", err
 	}
 
-	if err := p.contacts.Insert(contact.Number, contact); err != nil {
-		return err
+	contact.ID = newID(time.Now())
+	if err := p.insert(ctx, &contact); err != nil {
+		return "", err
 	}
+	return contact.ID, nil
+}
 
-	p.indexes.Add(contact)
-	return nil
+// Update updates the existing contact that owns the specified number. Any of the
+// contact's numbers may be used, and the contact keeps its ID.
+func (p *PhoneBook) Update(ctx context.Context, number string, update Contact) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, ok := p.contactByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found for number %s", number)
+	}
+	return p.update(ctx, existing, update)
 }
 
-// Update updates an existing contact for the specified number.
-func (p *PhoneBook) Update(number string, update Contact) error {
-	if number != update.Number {
-		if _, ok := p.Get(update.Number); ok {
-			return fmt.Errorf("contact already exists for new number %s", update.Number)
-		}
+// UpdateByID updates the existing contact with the specified ID.
+func (p *PhoneBook) UpdateByID(ctx context.Context, id string, update Contact) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, ok := p.contacts[id]
+	if !ok {
+		return fmt.Errorf("contact not found for ID %s", id)
 	}
-	p.Delete(number)
-	return p.Add(update)
+	return p.update(ctx, existing, update)
 }
 
-// Get returns the contact for the specified number.
+// Get returns the contact that owns the specified number, which may be in any
+// format accepted by the phone book's NumberPolicy.
 func (p *PhoneBook) Get(number string) (Contact, bool) {
-	return p.contacts.Get(number)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if contact, ok := p.contactByNumber(number); ok {
+		return contact.clone(), true
+	}
+	return Contact{}, false
+}
+
+// GetByID returns the contact with the specified ID.
+func (p *PhoneBook) GetByID(id string) (Contact, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if contact, ok := p.contacts[id]; ok {
+		return contact.clone(), true
+	}
+	return Contact{}, false
 }
 
-// FindByPrefix returns all contacts whose number starts with the specified prefix.
+// FindByPrefix returns all contacts with a number that starts with the specified
+// prefix. The prefix is normalized so that it matches the canonical form of the
+// stored numbers.
 func (p *PhoneBook) FindByPrefix(numberPrefix string) []Contact {
-	if contacts, ok := p.contacts.FindByPrefix(numberPrefix); ok {
-		return contacts
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if prefix, ok := p.policy.NormalizePrefix(numberPrefix); ok {
+		if ids, ok := p.numbers.FindByPrefix(numberKey(prefix)); ok {
+			return p.contactsByID(ids)
+		}
 	}
 	return []Contact{}
 }
@@ -78,17 +234,20 @@ func (p *PhoneBook) FindByPrefix(numberPrefix string) []Contact {
 // FindByName returns all contacts for the specified name. At least one of first
 // or last name is required for the search, or provide both for a full name search.
 func (p *PhoneBook) FindByName(firstName string, lastName string) []Contact {
-	var contacts []Contact
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var found []*Contact
 	var ok bool
 	if firstName != "" && lastName != "" {
-		contacts, ok = p.indexes.Get(indexFullName, firstName+lastName)
+		found, ok = p.indexes.Get(indexFullName, firstName+lastName)
 	} else if firstName != "" {
-		contacts, ok = p.indexes.Get(indexFirstName, firstName)
+		found, ok = p.indexes.Get(indexFirstName, firstName)
 	} else if lastName != "" {
-		contacts, ok = p.indexes.Get(indexLastName, lastName)
+		found, ok = p.indexes.Get(indexLastName, lastName)
 	}
 	if ok {
-		return contacts
+		return clones(found)
 	}
 	return []Contact{}
 }
@@ -96,8 +255,35 @@ func (p *PhoneBook) FindByName(firstName string, lastName string) []Contact {
 // FindByCity returns all contacts whose address is located within the specified
 // city.
 func (p *PhoneBook) FindByCity(city string) []Contact {
-	if contacts, ok := p.indexes.Get(indexCity, city); ok {
-		return contacts
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if found, ok := p.indexes.Get(indexCity, city); ok {
+		return clones(found)
+	}
+	return []Contact{}
+}
+
+// FindByEmail returns all contacts with the specified email address. Email
+// addresses are matched case-insensitively.
+func (p *PhoneBook) FindByEmail(email string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if found, ok := p.indexes.Get(indexEmail, emailKey(email)); ok {
+		return clones(found)
+	}
+	return []Contact{}
+}
+
+// FindByEmailDomain returns all contacts with an email address at the specified
+// domain (e.g. example.com).
+func (p *PhoneBook) FindByEmailDomain(domain string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if found, ok := p.indexes.Get(indexEmailDomain, emailKey(domain)); ok {
+		return clones(found)
 	}
 	return []Contact{}
 }
@@ -105,27 +291,341 @@ func (p *PhoneBook) FindByCity(city string) []Contact {
 // Find returns all contacts whose metadata contains the specified search term.
 // The search term must be a complete value (i.e. not half of a first name).
 func (p *PhoneBook) Find(search string) []Contact {
-	union := mapset.NewSet[Contact]()
-	if matched, err := regexp.Match("^\\d{1,10}$", []byte(search)); err == nil && matched {
-		union = union.Union(mapset.NewSet(p.FindByPrefix(search)...))
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var ids []string
+	if prefix, ok := p.policy.NormalizePrefix(search); ok && strings.ContainsAny(search, "0123456789") {
+		if found, ok := p.numbers.FindByPrefix(numberKey(prefix)); ok {
+			ids = append(ids, found...)
+		}
+	}
+	for _, name := range []string{indexFirstName, indexLastName, indexCity} {
+		if found, ok := p.indexes.Get(name, search); ok {
+			for _, contact := range found {
+				ids = append(ids, contact.ID)
+			}
+		}
+	}
+	if found, ok := p.indexes.Get(indexEmail, emailKey(search)); ok {
+		for _, contact := range found {
+			ids = append(ids, contact.ID)
+		}
+	}
+	for _, name := range p.customFieldNames() {
+		if !p.customFieldIndexed(name) {
+			continue
+		}
+		if found, ok := p.indexes.Get(p.customIndex(name, search)); ok {
+			for _, contact := range found {
+				ids = append(ids, contact.ID)
+			}
+		}
+	}
+	return p.contactsByID(ids)
+}
+
+// Delete deletes the contact that owns the specified number, including all of
+// the contact's other numbers and its history. If the trash is enabled, the
+// contact is moved to the trash instead. Deleting a number that does not belong
+// to a contact is a no-op.
+func (p *PhoneBook) Delete(ctx context.Context, number string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if contact, ok := p.contactByNumber(number); ok {
+		return p.remove(ctx, contact)
+	}
+	return nil
+}
+
+// DeleteByID deletes the contact with the specified ID and its history, or moves
+// it to the trash if the trash is enabled.
+func (p *PhoneBook) DeleteByID(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if contact, ok := p.contacts[id]; ok {
+		return p.remove(ctx, contact)
+	}
+	return nil
+}
+
+func (p *PhoneBook) update(ctx context.Context, existing *Contact, update Contact) error {
+	p.expireTrash()
+	update, _, err := p.stage(existing, update)
+	if err != nil {
+		return err
+	}
+	return p.replace(ctx, existing, &update)
+}
+
+// stage validates the contact and converts its numbers to canonical form, then
+// checks that it can replace the existing contact, or be added if the existing
+// contact is nil. The returned status classifies the error, if any.
+func (p *PhoneBook) stage(existing *Contact, contact Contact) (Contact, BatchStatus, error) {
+	if existing == nil && contact.ID != "" {
+		return Contact{}, BatchInvalid, fmt.Errorf("contact ID must be empty, IDs are assigned by the phone book")
+	} else if existing != nil && contact.ID != "" && contact.ID != existing.ID {
+		return Contact{}, BatchInvalid, fmt.Errorf("contact ID can not be changed")
+	}
+
+	if err := contact.Validate(); err != nil {
+		return Contact{}, BatchInvalid, err
+	}
+
+	if err := p.validateCustomFields(contact); err != nil {
+		return Contact{}, BatchInvalid, err
+	}
+
+	contact, err := p.normalize(contact)
+	if err != nil {
+		return Contact{}, BatchInvalid, err
+	}
+
+	if existing == nil {
+		if number, ok := p.numberConflict(contact, ""); ok {
+			return Contact{}, BatchDuplicate, fmt.Errorf("number already exists: %s", number)
+		}
+	} else if number, ok := p.numberConflict(contact, existing.ID); ok {
+		return Contact{}, BatchDuplicate, fmt.Errorf("contact already exists for new number %s", number)
+	}
+
+	if err := p.checkConstraints(existing, &contact); err != nil {
+		return Contact{}, BatchDuplicate, err
+	}
+
+	contact.Version = 1
+	if existing != nil {
+		contact.ID = existing.ID
+		contact.Version = existing.Version + 1
+	}
+	return contact, BatchOK, nil
+}
+
+// normalize returns a copy of the contact with each of its numbers converted to
+// canonical form.
+func (p *PhoneBook) normalize(contact Contact) (Contact, error) {
+	contact = contact.clone()
+	seen := map[string]bool{}
+	for i, number := range contact.Numbers {
+		canonical, err := p.policy.Normalize(number.Number)
+		if err != nil {
+			return Contact{}, err
+		} else if seen[canonical] {
+			return Contact{}, fmt.Errorf("duplicate phone number %s", canonical)
+		}
+		seen[canonical] = true
+		contact.Numbers[i].Number = canonical
+	}
+	return contact, nil
+}
+
+func (p *PhoneBook) contactByNumber(number string) (*Contact, bool) {
+	canonical, err := p.policy.Normalize(number)
+	if err != nil {
+		return nil, false
+	}
+	if id, ok := p.numbers.Get(numberKey(canonical)); ok {
+		return p.contacts[id], true
+	}
+	return nil, false
+}
+
+// numberConflict returns the first of the contact's numbers that belongs to a
+// contact other than the one with the specified ID, including contacts in the
+// trash.
+func (p *PhoneBook) numberConflict(contact Contact, id string) (string, bool) {
+	for _, number := range contact.NumberStrings() {
+		if owner, ok := p.numbers.Get(numberKey(number)); ok && owner != id {
+			return number, true
+		}
+		if owner, ok := p.reserved[numberKey(number)]; ok && owner != id {
+			return number, true
+		}
+	}
+	return "", false
+}
+
+// insert stores the contact and adds it to every index. The contact's numbers
+// must already be known to be available. Stored contacts are never modified,
+// updates replace them instead. Nothing is modified if the change can not be
+// audited.
+func (p *PhoneBook) insert(ctx context.Context, contact *Contact) error {
+	return p.apply(ctx, change{after: contact})
+}
+
+// replace replaces the existing contact with the updated contact, which must
+// have the same ID, and updates every index. Nothing is modified if the change
+// can not be audited.
+func (p *PhoneBook) replace(ctx context.Context, existing *Contact, updated *Contact) error {
+	return p.apply(ctx, change{before: existing, after: updated})
+}
+
+// remove deletes the contact, or moves it to the trash if the trash is enabled.
+// Otherwise the contact's history is also deleted, so that no data remains once
+// a contact is deleted. Nothing is modified if the change can not be audited.
+func (p *PhoneBook) remove(ctx context.Context, contact *Contact) error {
+	return p.apply(ctx, change{before: contact})
+}
+
+// change is a change to a contact, with a nil before contact for an added
+// contact, or a nil after contact for a deleted contact.
+type change struct {
+	before *Contact
+	after  *Contact
+	// permanent deletes the contact even if the trash is enabled.
+	permanent bool
+}
+
+// trashes reports whether the change moves a contact to the trash.
+func (p *PhoneBook) trashes(c change) bool {
+	return c.after == nil && p.trashEnabled && !c.permanent
+}
+
+// apply audits the change, stores it and then publishes it. Within a
+// transaction the change is stored straight away, so that the transaction reads
+// its own writes, but is only audited and published when the transaction
+// commits.
+func (p *PhoneBook) apply(ctx context.Context, c change) error {
+	if p.tx != nil {
+		p.store(c)
+		p.tx.changes = append(p.tx.changes, c)
+		return nil
+	}
+
+	if err := p.audit(c.auditRecord(ctx)); err != nil {
+		return err
+	}
+	p.store(c)
+	p.publish(ctx, c)
+	return nil
+}
+
+// store applies the change to the contacts and every index, moving a deleted
+// contact to the trash if the trash is enabled.
+func (p *PhoneBook) store(c change) {
+	switch {
+	case c.before == nil:
+		p.reindex(nil, c.after)
+	case c.after == nil:
+		p.reindex(c.before, nil)
+		if p.trashes(c) {
+			p.discard(c.before)
+		}
+	default:
+		p.reindex(c.before, c.after)
+	}
+}
+
+// undo reverts a change applied by store.
+func (p *PhoneBook) undo(c change) {
+	switch {
+	case c.before == nil:
+		p.reindex(c.after, nil)
+	case c.after == nil:
+		if p.trashes(c) {
+			p.release(c.before)
+		}
+		p.reindex(nil, c.before)
+	default:
+		p.reindex(c.after, c.before)
+	}
+}
+
+// publish records the stored change in the contact's history and publishes it to
+// the change feed. The history of a deleted contact is deleted instead, unless
+// the trash is enabled.
+func (p *PhoneBook) publish(ctx context.Context, c change) {
+	if c.after == nil && !p.trashes(c) {
+		delete(p.history, c.before.ID)
+		p.feed.publish(c.before, nil)
+		p.feed.redact(c.before.ID)
+		return
+	}
+	p.record(ctx, c.before, c.after)
+	p.feed.publish(c.before, c.after)
+}
+
+// reindex replaces the existing contact with the updated contact in the phone
+// book and every index. Pass a nil existing contact to add a contact, or a nil
+// updated contact to remove one.
+func (p *PhoneBook) reindex(existing *Contact, updated *Contact) {
+	p.reindexTries(existing, updated)
+
+	switch {
+	case existing == nil:
+		p.indexes.Add(updated)
+		p.contacts[updated.ID] = updated
+	case updated == nil:
+		p.indexes.Delete(existing)
+		delete(p.contacts, existing.ID)
+	default:
+		p.indexes.Update(existing, updated)
+		p.contacts[updated.ID] = updated
+	}
+}
+
+// reindexTries replaces the existing contact with the updated contact in the
+// number, suffix and keypad tries. Either contact may be nil, as for reindex.
+func (p *PhoneBook) reindexTries(existing *Contact, updated *Contact) {
+	if existing != nil {
+		for _, number := range existing.NumberStrings() {
+			p.numbers.Delete(numberKey(number))
+			p.suffixes.Delete(reverse(numberKey(number)))
+		}
+		p.removeKeypadNames(existing)
+	}
+	if updated != nil {
+		for _, number := range updated.NumberStrings() {
+			_ = p.numbers.Insert(numberKey(number), updated.ID)
+			_ = p.suffixes.Insert(reverse(numberKey(number)), updated.ID)
+		}
+		p.addKeypadNames(updated)
+	}
+}
+
+// contactsByID returns the contacts for the specified IDs, ignoring any
+// duplicate IDs.
+func (p *PhoneBook) contactsByID(ids []string) []Contact {
+	found := []Contact{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if contact, ok := p.contacts[id]; ok && !seen[id] {
+			seen[id] = true
+			found = append(found, contact.clone())
+		}
+	}
+	return found
+}
+
+func clones(contacts []*Contact) []Contact {
+	cloned := make([]Contact, len(contacts))
+	for i, contact := range contacts {
+		cloned[i] = contact.clone()
+	}
+	return cloned
+}
+
+func emailKeys(contact *Contact) []string {
+	keys := make([]string, len(contact.Emails))
+	for i, email := range contact.Emails {
+		keys[i] = emailKey(email.Address)
 	}
-	union = union.Union(mapset.NewSet(p.FindByName(search, "")...))
-	union = union.Union(mapset.NewSet(p.FindByName("", search)...))
-	union = union.Union(mapset.NewSet(p.FindByCity(search)...))
-	return union.ToSlice()
+	return keys
 }
 
-// Delete deletes the contact for the specified number.
-func (p *PhoneBook) Delete(number string) {
-	if contact, ok := p.Get(number); ok {
-		p.contacts.Delete(number)
-		p.indexes.Delete(contact)
+func emailDomainKeys(contact *Contact) []string {
+	keys := make([]string, len(contact.Emails))
+	for i, email := range contact.Emails {
+		keys[i] = emailKey(email.Domain())
 	}
+	return keys
 }
 
 func cityFromAddress(address string) (string, bool) {
-	if address != "" {
-		return strings.Split(address, ", ")[1], true
+	if parts := addressParts(address); len(parts) == 5 && parts[1] != "" {
+		return parts[1], true
 	}
 	return "", false
 }
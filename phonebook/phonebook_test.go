@@ -1,6 +1,7 @@
 package phonebook
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -10,32 +11,34 @@ import (
 func TestPhoneBook_Add(t *testing.T) {
 	phoneBook := New()
 	contact := Contact{
-		Number:    "0123456789",
+		Numbers:   mobile("0123456789"),
 		FirstName: "Foo",
 		LastName:  "Bar",
 	}
-	require.NoError(t, phoneBook.Add(contact))
+	add(t, phoneBook, &contact)
 }
 
 func TestPhoneBook_Add_duplicateError(t *testing.T) {
 	phoneBook := New()
 	contact := Contact{
-		Number:    "0123456789",
+		Numbers:   mobile("0123456789"),
 		FirstName: "Foo",
 		LastName:  "Bar",
 	}
-	require.NoError(t, phoneBook.Add(contact))
-	require.Error(t, phoneBook.Add(contact))
+	add(t, phoneBook, &contact)
+	contact.ID = ""
+	_, err := phoneBook.Add(context.Background(), contact)
+	require.Error(t, err)
 }
 
 func TestPhoneBook_Get(t *testing.T) {
 	phoneBook := New()
 	want := Contact{
-		Number:    "0123456789",
+		Numbers:   mobile("0123456789"),
 		FirstName: "Foo",
 		LastName:  "Bar",
 	}
-	require.NoError(t, phoneBook.Add(want))
+	add(t, phoneBook, &want)
 
 	tests := []struct {
 		name   string
@@ -44,7 +47,7 @@ func TestPhoneBook_Get(t *testing.T) {
 	}{
 		{
 			name:   "success",
-			number: want.Number,
+			number: want.Numbers[0].Number,
 			found:  true,
 		},
 		{
@@ -67,15 +70,55 @@ func TestPhoneBook_Get(t *testing.T) {
 	}
 }
 
+func TestPhoneBook_Get_multipleNumbers(t *testing.T) {
+	phoneBook := New()
+	want := Contact{
+		Numbers: []PhoneNumber{
+			{Type: Mobile, Number: "0123456789"},
+			{Type: Home, Number: "9876543210"},
+			{Type: Custom, Label: "pager", Number: "5555555555"},
+		},
+		FirstName: "Foo",
+		LastName:  "Bar",
+	}
+	add(t, phoneBook, &want)
+
+	for _, number := range want.NumberStrings() {
+		got, ok := phoneBook.Get(number)
+		require.True(t, ok)
+		require.Equal(t, want, got)
+	}
+}
+
+func TestPhoneBook_Add_duplicateSecondaryNumberError(t *testing.T) {
+	phoneBook := New()
+	existing := Contact{
+		Numbers:   []PhoneNumber{{Type: Mobile, Number: "0123456789"}, {Type: Work, Number: "9876543210"}},
+		FirstName: "Foo",
+		LastName:  "Bar",
+	}
+	conflicting := Contact{
+		Numbers:   []PhoneNumber{{Type: Mobile, Number: "5555555555"}, {Type: Home, Number: "9876543210"}},
+		FirstName: "Lorem",
+		LastName:  "Ipsum",
+	}
+	add(t, phoneBook, &existing)
+	_, err := phoneBook.Add(context.Background(), conflicting)
+	require.EqualError(t, err, "number already exists: 9876543210")
+
+	_, ok := phoneBook.Get("5555555555")
+	require.False(t, ok)
+}
+
 func TestPhoneBook_FindByPrefix(t *testing.T) {
 	phoneBook := New()
 	prefix := "11"
-	want1 := Contact{Number: prefix + "23456789", FirstName: "One", LastName: "One"}
-	want2 := Contact{Number: prefix + "76543210", FirstName: "Two", LastName: "Two"}
-	dummy := Contact{Number: "1232167890", FirstName: "Three", LastName: "Three"}
-	require.NoError(t, phoneBook.Add(want1))
-	require.NoError(t, phoneBook.Add(want2))
-	require.NoError(t, phoneBook.Add(dummy))
+	want1 := Contact{Numbers: mobile(prefix + "23456789"), FirstName: "One", LastName: "One"}
+	want2 := Contact{Numbers: mobile(prefix + "76543210"), FirstName: "Two", LastName: "Two"}
+	dummy := Contact{Numbers: mobile("1232167890"), FirstName: "Three", LastName: "Three"}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
 
 	tests := []struct {
 		name   string
@@ -110,13 +153,13 @@ func TestPhoneBook_FindByName(t *testing.T) {
 	phoneBook := New()
 
 	firstName, lastName := "One", "Two"
-	want1 := Contact{Number: "0123456789", FirstName: firstName, LastName: lastName}
-	want2 := Contact{Number: "9876543210", FirstName: firstName, LastName: lastName}
-	dummy := Contact{Number: "5432167890", FirstName: "Three", LastName: "Three"}
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: firstName, LastName: lastName}
+	want2 := Contact{Numbers: mobile("9876543210"), FirstName: firstName, LastName: lastName}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three"}
 
-	require.NoError(t, phoneBook.Add(want1))
-	require.NoError(t, phoneBook.Add(want2))
-	require.NoError(t, phoneBook.Add(dummy))
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
 
 	tests := []struct {
 		name      string
@@ -174,12 +217,12 @@ func TestPhoneBook_FindByCity(t *testing.T) {
 	phoneBook := New()
 	city := "Foo City"
 	address := fmt.Sprintf("1 Foo St, %s, Foo State, 1111, Foo Country", city)
-	want1 := Contact{Number: "0123456789", FirstName: "One", LastName: "One", Address: address}
-	want2 := Contact{Number: "9876543210", FirstName: "Two", LastName: "Two", Address: address}
-	dummy := Contact{Number: "5432167890", FirstName: "Three", LastName: "Three", Address: "1 Dummy St, Dummy City, Dummy State, 2222, Dummy Country"}
-	require.NoError(t, phoneBook.Add(want1))
-	require.NoError(t, phoneBook.Add(want2))
-	require.NoError(t, phoneBook.Add(dummy))
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: address}
+	want2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: address}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Address: "1 Dummy St, Dummy City, Dummy State, 2222, Dummy Country"}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
 
 	tests := []struct {
 		name  string
@@ -210,18 +253,26 @@ func TestPhoneBook_FindByCity(t *testing.T) {
 	}
 }
 
+func TestPhoneBook_FindByCity_irregularAddress(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: "1 St,City,State,2000,AU"}
+	add(t, phoneBook, &contact)
+
+	require.Equal(t, []Contact{contact}, phoneBook.FindByCity("City"))
+}
+
 func TestPhoneBook_Find(t *testing.T) {
 	phoneBook := New()
 	prefix, firstName, lastName, city := "0011", "Foo", "Bar", "Foo City"
 	common := "Common"
-	want1 := Contact{Number: prefix + "223344", FirstName: firstName, LastName: lastName, Address: newAddress(city)}
-	want2 := Contact{Number: prefix + "225566", FirstName: firstName, LastName: lastName, Address: newAddress(city)}
-	want3 := Contact{Number: "1111111111", FirstName: common, LastName: "Three", Address: newAddress("Dummy")}
-	want4 := Contact{Number: "9999999999", FirstName: "Four", LastName: "Four", Address: newAddress(common)}
-	require.NoError(t, phoneBook.Add(want1))
-	require.NoError(t, phoneBook.Add(want2))
-	require.NoError(t, phoneBook.Add(want3))
-	require.NoError(t, phoneBook.Add(want4))
+	want1 := Contact{Numbers: mobile(prefix + "223344"), FirstName: firstName, LastName: lastName, Address: newAddress(city)}
+	want2 := Contact{Numbers: mobile(prefix + "225566"), FirstName: firstName, LastName: lastName, Address: newAddress(city)}
+	want3 := Contact{Numbers: mobile("1111111111"), FirstName: common, LastName: "Three", Address: newAddress("Dummy")}
+	want4 := Contact{Numbers: mobile("9999999999"), FirstName: "Four", LastName: "Four", Address: newAddress(common)}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &want3)
+	add(t, phoneBook, &want4)
 
 	tests := []struct {
 		name   string
@@ -277,13 +328,13 @@ func TestPhoneBook_Delete(t *testing.T) {
 	phoneBook := New()
 	prefix, city := "01", "Foo City"
 	want := Contact{
-		Number:    prefix + "23456789",
+		Numbers:   mobile(prefix + "23456789"),
 		FirstName: "Foo",
 		LastName:  "Bar",
 		Address:   newAddress(city),
 	}
-	require.NoError(t, phoneBook.Add(want))
-	phoneBook.Delete(want.Number)
+	add(t, phoneBook, &want)
+	require.NoError(t, phoneBook.Delete(context.Background(), want.Numbers[0].Number))
 
 	tests := []struct {
 		name string
@@ -293,7 +344,7 @@ func TestPhoneBook_Delete(t *testing.T) {
 		{
 			name: "get fail",
 			fn: func() any {
-				contact, _ := phoneBook.Get(want.Number)
+				contact, _ := phoneBook.Get(want.Numbers[0].Number)
 				return contact
 			},
 		},
@@ -331,19 +382,21 @@ func TestPhoneBook_Update(t *testing.T) {
 	phoneBook := New()
 	oldPrefix, updatedPrefix, oldCity, updatedCity := "00", "01", "Dummy City", "Updated City"
 	old := Contact{
-		Number:    oldPrefix + "00000000",
+		Numbers:   mobile(oldPrefix + "00000000"),
 		FirstName: "Dummy",
 		LastName:  "Dummy",
 		Address:   newAddress(oldCity),
 	}
 	updated := Contact{
-		Number:    updatedPrefix + "23456789",
+		Numbers:   mobile(updatedPrefix + "23456789"),
 		FirstName: "Foo",
 		LastName:  "Bar",
 		Address:   newAddress(updatedCity),
 	}
-	require.NoError(t, phoneBook.Add(old))
-	require.NoError(t, phoneBook.Update(old.Number, updated))
+	add(t, phoneBook, &old)
+	require.NoError(t, phoneBook.Update(context.Background(), old.Numbers[0].Number, updated))
+	updated.ID = old.ID
+	updated.Version = 2
 
 	tests := []struct {
 		name  string
@@ -354,7 +407,7 @@ func TestPhoneBook_Update(t *testing.T) {
 		{
 			name: "get updated contact success",
 			fn: func() any {
-				contact, _ := phoneBook.Get(updated.Number)
+				contact, _ := phoneBook.Get(updated.Numbers[0].Number)
 				return contact
 			},
 			found: true,
@@ -363,7 +416,7 @@ func TestPhoneBook_Update(t *testing.T) {
 		{
 			name: "get old contact fail",
 			fn: func() any {
-				contact, _ := phoneBook.Get(old.Number)
+				contact, _ := phoneBook.Get(old.Numbers[0].Number)
 				return contact
 			},
 			found: false,
@@ -443,25 +496,280 @@ func TestPhoneBook_Update_contactExistsError(t *testing.T) {
 	oldPrefix, updatedPrefix := "00", "01"
 
 	old := Contact{
-		Number:    oldPrefix + "00000000",
+		Numbers:   mobile(oldPrefix + "00000000"),
 		FirstName: "Dummy",
 		LastName:  "Dummy",
 	}
 
 	existing := Contact{
-		Number:    updatedPrefix + "23456789",
+		Numbers:   mobile(updatedPrefix + "23456789"),
 		FirstName: "Foo",
 		LastName:  "Bar",
 	}
 
 	updated := existing
 
-	require.NoError(t, phoneBook.Add(old))
-	require.NoError(t, phoneBook.Add(existing))
-	err := phoneBook.Update(old.Number, updated)
-	require.EqualError(t, err, fmt.Sprintf("contact already exists for new number %s", existing.Number))
+	add(t, phoneBook, &old)
+	add(t, phoneBook, &existing)
+	err := phoneBook.Update(context.Background(), old.Numbers[0].Number, updated)
+	require.EqualError(t, err, fmt.Sprintf("contact already exists for new number %s", existing.Numbers[0].Number))
+}
+
+func TestPhoneBook_Update_multipleNumbers(t *testing.T) {
+	phoneBook := New()
+	old := Contact{
+		Numbers:   []PhoneNumber{{Type: Mobile, Number: "0123456789"}, {Type: Work, Number: "9876543210"}},
+		FirstName: "Foo",
+		LastName:  "Bar",
+	}
+	updated := Contact{
+		Numbers:   []PhoneNumber{{Type: Mobile, Number: "0123456789"}, {Type: Home, Number: "5555555555"}},
+		FirstName: "Foo",
+		LastName:  "Bar",
+	}
+	add(t, phoneBook, &old)
+	require.NoError(t, phoneBook.Update(context.Background(), "9876543210", updated))
+	updated.ID = old.ID
+	updated.Version = 2
+
+	_, ok := phoneBook.Get("9876543210")
+	require.False(t, ok)
+	got, ok := phoneBook.Get("5555555555")
+	require.True(t, ok)
+	require.Equal(t, updated, got)
+	require.Equal(t, []Contact{updated}, phoneBook.FindByPrefix("0"))
+}
+
+func TestPhoneBook_Update_notFoundError(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{
+		Numbers:   mobile("0123456789"),
+		FirstName: "Foo",
+		LastName:  "Bar",
+	}
+	err := phoneBook.Update(context.Background(), contact.Numbers[0].Number, contact)
+	require.EqualError(t, err, "contact not found for number 0123456789")
 }
 
 func newAddress(city string) string {
 	return fmt.Sprintf("1 Foo St, %s, Foo State, 1111, Foo Country", city)
 }
+
+func mobile(number string) []PhoneNumber {
+	return []PhoneNumber{{Type: Mobile, Number: number}}
+}
+
+func TestPhoneBook_Add_idAssigned(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "Foo", LastName: "Bar"}
+	add(t, phoneBook, &contact)
+	require.NotEmpty(t, contact.ID)
+
+	_, err := phoneBook.Add(context.Background(), Contact{ID: "custom", Numbers: mobile("9876543210"), FirstName: "Foo", LastName: "Bar"})
+	require.EqualError(t, err, "contact ID must be empty, IDs are assigned by the phone book")
+}
+
+func TestPhoneBook_GetByID(t *testing.T) {
+	phoneBook := New()
+	want := Contact{Numbers: mobile("0123456789"), FirstName: "Foo", LastName: "Bar"}
+	add(t, phoneBook, &want)
+
+	got, ok := phoneBook.GetByID(want.ID)
+	require.True(t, ok)
+	require.Equal(t, want, got)
+
+	got, ok = phoneBook.GetByID("unknown")
+	require.False(t, ok)
+	require.Empty(t, got)
+}
+
+func TestPhoneBook_UpdateByID(t *testing.T) {
+	phoneBook := New()
+	old := Contact{Numbers: mobile("0123456789"), FirstName: "Foo", LastName: "Bar"}
+	add(t, phoneBook, &old)
+
+	updated := Contact{Numbers: mobile("9876543210"), FirstName: "Lorem", LastName: "Ipsum"}
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), old.ID, updated))
+	updated.ID = old.ID
+	updated.Version = 2
+
+	got, ok := phoneBook.GetByID(old.ID)
+	require.True(t, ok)
+	require.Equal(t, updated, got)
+	got, ok = phoneBook.Get("9876543210")
+	require.True(t, ok)
+	require.Equal(t, updated, got)
+	_, ok = phoneBook.Get("0123456789")
+	require.False(t, ok)
+	require.Empty(t, phoneBook.FindByName("Foo", ""))
+
+	require.EqualError(t, phoneBook.UpdateByID(context.Background(), "unknown", updated), "contact not found for ID unknown")
+	updated.ID = "changed"
+	require.EqualError(t, phoneBook.UpdateByID(context.Background(), old.ID, updated), "contact ID can not be changed")
+}
+
+func TestPhoneBook_DeleteByID(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "Foo", LastName: "Bar", Address: newAddress("Foo City")}
+	add(t, phoneBook, &contact)
+
+	require.NoError(t, phoneBook.DeleteByID(context.Background(), contact.ID))
+
+	_, ok := phoneBook.GetByID(contact.ID)
+	require.False(t, ok)
+	_, ok = phoneBook.Get(contact.Numbers[0].Number)
+	require.False(t, ok)
+	require.Empty(t, phoneBook.FindByName("Foo", "Bar"))
+	require.Empty(t, phoneBook.FindByCity("Foo City"))
+}
+
+func TestPhoneBook_numberPolicy(t *testing.T) {
+	phoneBook := New(WithNumberPolicy(E164Policy{DefaultRegion: "AU"}))
+	want := Contact{
+		Numbers:   []PhoneNumber{{Type: Mobile, Number: "0410 000 000"}, {Type: Work, Number: "+1 (555) 123-4567"}},
+		FirstName: "Foo",
+		LastName:  "Bar",
+	}
+	add(t, phoneBook, &want)
+	want.Numbers[0].Number = "+61410000000"
+	want.Numbers[1].Number = "+15551234567"
+
+	for _, number := range []string{"+61410000000", "0410000000", "+61 410 000 000", "+15551234567"} {
+		got, ok := phoneBook.Get(number)
+		require.True(t, ok, number)
+		require.Equal(t, want, got)
+	}
+
+	require.Equal(t, []Contact{want}, phoneBook.FindByPrefix("0410"))
+	require.Equal(t, []Contact{want}, phoneBook.FindByPrefix("+1 555"))
+	require.Equal(t, []Contact{want}, phoneBook.Find("+61 4"))
+	require.Empty(t, phoneBook.FindByPrefix("+44"))
+
+	_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile("+61 410 000 000"), FirstName: "Lorem", LastName: "Ipsum"})
+	require.EqualError(t, err, "number already exists: +61410000000")
+	_, err = phoneBook.Add(context.Background(), Contact{Numbers: mobile("+999 1234"), FirstName: "Lorem", LastName: "Ipsum"})
+	require.EqualError(t, err, "phone number +999 1234 has an unknown country calling code")
+}
+
+func TestPhoneBook_numberPolicy_national(t *testing.T) {
+	tests := []struct {
+		region        string
+		national      string
+		international string
+		prefix        string
+	}{
+		{region: "DE", national: "030 1234567", international: "+49 30 1234567", prefix: "030"},
+		{region: "NZ", national: "021 123 4567", international: "+64 21 123 4567", prefix: "021"},
+		{region: "JP", national: "03 1234 5678", international: "+81 3 1234 5678", prefix: "03"},
+		{region: "GB", national: "0163 296 000", international: "+44 163 296 000", prefix: "0163"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			policy := E164Policy{DefaultRegion: tt.region}
+			want, err := policy.Normalize(tt.international)
+			require.NoError(t, err)
+			got, err := policy.Normalize(tt.national)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+
+			phoneBook := New(WithNumberPolicy(policy))
+			contact := Contact{Numbers: mobile(tt.national), FirstName: "Foo", LastName: "Bar"}
+			add(t, phoneBook, &contact)
+			contact.Numbers[0].Number = want
+
+			found, ok := phoneBook.Get(tt.international)
+			require.True(t, ok)
+			require.Equal(t, contact, found)
+			require.Equal(t, []Contact{contact}, phoneBook.FindByPrefix(tt.prefix))
+			require.Equal(t, []Contact{contact}, phoneBook.FindByPrefix(want[:len(want)-4]))
+		})
+	}
+}
+
+func TestPhoneBook_Add_duplicateNormalizedNumberError(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{
+		Numbers:   []PhoneNumber{{Type: Mobile, Number: "0123456789"}, {Type: Home, Number: "(01) 2345 6789"}},
+		FirstName: "Foo",
+		LastName:  "Bar",
+	}
+	_, err := phoneBook.Add(context.Background(), contact)
+	require.EqualError(t, err, "duplicate phone number 0123456789")
+}
+
+func TestPhoneBook_FindByEmail(t *testing.T) {
+	phoneBook := New()
+	email := "foo@example.com"
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Emails: []Email{{Type: Personal, Address: email}}}
+	want2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Emails: []Email{{Type: Business, Address: "FOO@example.com"}}}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Emails: []Email{{Type: Personal, Address: "bar@example.com"}}}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
+
+	require.ElementsMatch(t, []Contact{want1, want2}, phoneBook.FindByEmail(email))
+	require.ElementsMatch(t, []Contact{want1, want2}, phoneBook.Find(email))
+	require.Empty(t, phoneBook.FindByEmail("random@example.com"))
+
+	require.NoError(t, phoneBook.Delete(context.Background(), want1.Numbers[0].Number))
+	require.Equal(t, []Contact{want2}, phoneBook.FindByEmail(email))
+
+	updated := want2
+	updated.Emails = []Email{{Type: Business, Address: "two@example.org"}}
+	require.NoError(t, phoneBook.Update(context.Background(), want2.Numbers[0].Number, updated))
+	updated.Version = 2
+	require.Empty(t, phoneBook.FindByEmail(email))
+	require.Equal(t, []Contact{updated}, phoneBook.FindByEmail("two@example.org"))
+}
+
+func TestPhoneBook_FindByEmailDomain(t *testing.T) {
+	phoneBook := New()
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Emails: []Email{{Type: Personal, Address: "one@example.com"}}}
+	want2 := Contact{
+		Numbers:   mobile("9876543210"),
+		FirstName: "Two",
+		LastName:  "Two",
+		Emails:    []Email{{Type: Personal, Address: "two@example.org"}, {Type: Business, Address: "two@Example.com"}},
+	}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Emails: []Email{{Type: Personal, Address: "three@example.org"}}}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
+
+	require.ElementsMatch(t, []Contact{want1, want2}, phoneBook.FindByEmailDomain("example.com"))
+	require.Empty(t, phoneBook.FindByEmailDomain("example.net"))
+
+	require.NoError(t, phoneBook.Delete(context.Background(), want2.Numbers[0].Number))
+	require.Equal(t, []Contact{want1}, phoneBook.FindByEmailDomain("example.com"))
+	require.Equal(t, []Contact{dummy}, phoneBook.FindByEmailDomain("example.org"))
+}
+
+func TestPhoneBook_uniqueEmails(t *testing.T) {
+	phoneBook := New(WithUniqueEmails())
+	existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Emails: []Email{{Type: Personal, Address: "foo@example.com"}}}
+	other := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+	add(t, phoneBook, &existing)
+	add(t, phoneBook, &other)
+
+	_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Emails: []Email{{Type: Business, Address: "Foo@example.com"}}})
+	require.Equal(t, &ConstraintError{Constraint: "email", Key: "foo@example.com", ContactID: existing.ID}, err)
+
+	other.Emails = existing.Emails
+	err = phoneBook.Update(context.Background(), other.Numbers[0].Number, other)
+	require.Equal(t, &ConstraintError{Constraint: "email", Key: "foo@example.com", ContactID: existing.ID}, err)
+	require.Equal(t, []Contact{existing}, phoneBook.FindByEmail("foo@example.com"))
+
+	// Updating the contact that owns the email address is allowed
+	existing.FirstName = "Updated"
+	require.NoError(t, phoneBook.Update(context.Background(), existing.Numbers[0].Number, existing))
+}
+
+// add adds the contact to the phone book and sets the ID and version assigned
+// to it.
+func add(t *testing.T, phoneBook *PhoneBook, contact *Contact) {
+	id, err := phoneBook.Add(context.Background(), *contact)
+	require.NoError(t, err)
+	contact.ID = id
+	contact.Version = 1
+}
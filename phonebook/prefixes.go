@@ -0,0 +1,107 @@
+package phonebook
+
+import (
+	"fmt"
+
+	"github.com/joshjon/go-phonebook/internal/trie"
+)
+
+// PrefixRule holds the metadata of the numbers that start with a prefix, which
+// is used to route calls.
+type PrefixRule struct {
+	// Prefix is the number prefix in canonical form (e.g. 61410 for numbers in
+	// E.164 format, or 0410 for ten digit numbers). Formatting and a leading plus
+	// sign are ignored.
+	Prefix  string
+	Area    string
+	Carrier string
+	// Region is the ISO 3166-1 alpha-2 code of the region of the numbers.
+	Region string
+}
+
+// PrefixTable is a table of PrefixRules, which are matched to numbers by their
+// longest prefix. A more specific rule (e.g. a carrier block) takes precedence
+// over a less specific rule (e.g. an area code).
+type PrefixTable struct {
+	rules *trie.NumberTrie[PrefixRule]
+}
+
+// NewPrefixTable returns a new PrefixTable holding the specified rules.
+func NewPrefixTable(rules ...PrefixRule) (*PrefixTable, error) {
+	table := &PrefixTable{rules: trie.NewNumberTrie[PrefixRule]()}
+	for _, rule := range rules {
+		if err := table.Add(rule); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+// Add adds a rule to the table. Each prefix may only have one rule. A table is
+// not safe for concurrent use while rules are added.
+func (t *PrefixTable) Add(rule PrefixRule) error {
+	prefix := numberKey(formatting.ReplaceAllString(rule.Prefix, ""))
+	if prefix == "" || !isDigits(prefix) {
+		return fmt.Errorf("invalid prefix '%s'", rule.Prefix)
+	}
+	if _, ok := t.rules.Get(prefix); ok {
+		return fmt.Errorf("prefix rule already exists: %s", prefix)
+	}
+	rule.Prefix = prefix
+	return t.rules.Insert(prefix, rule)
+}
+
+// clone returns a copy of the table.
+func (t *PrefixTable) clone() *PrefixTable {
+	cloned := &PrefixTable{rules: trie.NewNumberTrie[PrefixRule]()}
+	rules, _ := t.rules.FindByPrefix("")
+	for _, rule := range rules {
+		_ = cloned.rules.Insert(rule.Prefix, rule)
+	}
+	return cloned
+}
+
+// Lookup returns the rule with the longest prefix of the canonical number.
+func (t *PrefixTable) Lookup(number string) (PrefixRule, bool) {
+	_, rule, ok := t.rules.LongestPrefix(numberKey(number))
+	return rule, ok
+}
+
+// Classification is the result of classifying a number with a phone book's
+// PrefixTable.
+type Classification struct {
+	// Number is the classified number in canonical form.
+	Number string
+	// Rule is the rule with the longest prefix of the number, which is empty if
+	// no rule matched.
+	Rule    PrefixRule
+	Matched bool
+	// Contact is the contact that owns the number, or nil if the number does not
+	// belong to a contact.
+	Contact *Contact
+}
+
+// Classify returns the rule of the phone book's PrefixTable that matches the
+// specified number, along with the contact that owns it. The number may be in
+// any format accepted by the phone book's NumberPolicy.
+func (p *PhoneBook) Classify(number string) (Classification, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.prefixes == nil {
+		return Classification{}, fmt.Errorf("prefix table not configured")
+	}
+
+	canonical, err := p.policy.Normalize(number)
+	if err != nil {
+		return Classification{}, err
+	}
+
+	classification := Classification{Number: canonical}
+	classification.Rule, classification.Matched = p.prefixes.Lookup(canonical)
+	if contact, ok := p.contactByNumber(canonical); ok {
+		cloned := contact.clone()
+		classification.Contact = &cloned
+	}
+	return classification, nil
+}
@@ -0,0 +1,83 @@
+package phonebook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixTable(t *testing.T) {
+	table, err := NewPrefixTable(
+		PrefixRule{Prefix: "+61", Region: "AU"},
+		PrefixRule{Prefix: "+61 4", Area: "Mobile", Region: "AU"},
+		PrefixRule{Prefix: "+61 410", Area: "Mobile", Carrier: "Foo Telecom", Region: "AU"},
+		PrefixRule{Prefix: "+61 2", Area: "NSW", Region: "AU"},
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		number    string
+		want      PrefixRule
+		wantFound bool
+	}{
+		{name: "carrier", number: "+61410000000", want: PrefixRule{Prefix: "61410", Area: "Mobile", Carrier: "Foo Telecom", Region: "AU"}, wantFound: true},
+		{name: "area", number: "+61299999999", want: PrefixRule{Prefix: "612", Area: "NSW", Region: "AU"}, wantFound: true},
+		{name: "region", number: "+61399999999", want: PrefixRule{Prefix: "61", Region: "AU"}, wantFound: true},
+		{name: "no rule", number: "+64211234567", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := table.Lookup(tt.number)
+			require.Equal(t, tt.wantFound, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	require.EqualError(t, table.Add(PrefixRule{Prefix: "+61 4"}), "prefix rule already exists: 614")
+	require.EqualError(t, table.Add(PrefixRule{Prefix: "61a"}), "invalid prefix '61a'")
+	require.EqualError(t, table.Add(PrefixRule{}), "invalid prefix ''")
+	_, err = NewPrefixTable(PrefixRule{Prefix: "1"}, PrefixRule{Prefix: "1"})
+	require.EqualError(t, err, "prefix rule already exists: 1")
+}
+
+func TestPhoneBook_Classify(t *testing.T) {
+	table, err := NewPrefixTable(
+		PrefixRule{Prefix: "+61 4", Area: "Mobile", Region: "AU"},
+		PrefixRule{Prefix: "+61 410", Carrier: "Foo Telecom", Region: "AU"},
+	)
+	require.NoError(t, err)
+	phoneBook := New(WithNumberPolicy(E164Policy{DefaultRegion: "AU"}), WithPrefixTable(table))
+	contact := Contact{Numbers: mobile("+61410000000"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+
+	got, err := phoneBook.Classify("0410 000 000")
+	require.NoError(t, err)
+	require.Equal(t, Classification{
+		Number:  "+61410000000",
+		Rule:    PrefixRule{Prefix: "61410", Carrier: "Foo Telecom", Region: "AU"},
+		Matched: true,
+		Contact: &contact,
+	}, got)
+
+	got, err = phoneBook.Classify("0420 000 000")
+	require.NoError(t, err)
+	require.Equal(t, Classification{
+		Number:  "+61420000000",
+		Rule:    PrefixRule{Prefix: "614", Area: "Mobile", Region: "AU"},
+		Matched: true,
+	}, got)
+
+	// Rules added to the table after it is configured are not used
+	require.NoError(t, table.Add(PrefixRule{Prefix: "+64", Region: "NZ"}))
+	got, err = phoneBook.Classify("+64 21 123 4567")
+	require.NoError(t, err)
+	require.Equal(t, Classification{Number: "+64211234567"}, got)
+
+	_, err = phoneBook.Classify("abc")
+	require.EqualError(t, err, "phone number abc contains invalid characters")
+
+	_, err = New().Classify("0123456789")
+	require.EqualError(t, err, "prefix table not configured")
+}
@@ -0,0 +1,191 @@
+package phonebook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joshjon/go-phonebook/internal/index"
+)
+
+// Fields of the built-in ordered indexes, which can be queried with FindByRange.
+const (
+	rangeFirstName  = "first_name"
+	rangeLastName   = "last_name"
+	rangePostalCode = "postal_code"
+	rangeNumber     = "number"
+)
+
+// RangeOptions configures a range query.
+type RangeOptions struct {
+	// Limit is the maximum number of contacts returned. Zero means no limit.
+	Limit int
+	// Descending returns contacts in descending order of the field.
+	Descending bool
+}
+
+// FindByRange returns all contacts with a value for the field between lo and hi
+// inclusive, sorted by the field. An empty bound is unbounded, so an empty lo and
+// hi lists every contact with a value for the field in sorted order. Values are
+// compared lexicographically (e.g. last names between "M" and "P~", or postal
+// codes between "2000" and "2999").
+//
+// The built-in fields are first_name, last_name, postal_code and number. Number
+// bounds may be in any format accepted by the phone book's NumberPolicy, and a
+// contact with several numbers in the range is only returned once. Additional
+// fields can be registered with WithOrderedIndex or AddOrderedIndex.
+func (p *PhoneBook) FindByRange(field string, lo string, hi string, opts RangeOptions) ([]Contact, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	idx, ok := p.ordered[field]
+	if !ok {
+		return nil, fmt.Errorf("range field %s not found", field)
+	}
+
+	if field == rangeNumber {
+		var err error
+		if lo, err = p.numberBound(lo); err != nil {
+			return nil, err
+		}
+		if hi, err = p.numberBound(hi); err != nil {
+			return nil, err
+		}
+	}
+
+	return clones(idx.Range(lo, hi, index.RangeOptions{Limit: opts.Limit, Descending: opts.Descending})), nil
+}
+
+// FindByNumberRange returns all contacts with a number in the block between lo
+// and hi inclusive (e.g. 0410000000 to 0410049999), sorted by their lowest
+// number in the block. The bounds may be in any format accepted by the phone
+// book's NumberPolicy, and must have the same length once normalized.
+func (p *PhoneBook) FindByNumberRange(lo string, hi string) ([]Contact, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	loKey, hiKey, err := p.numberRange(lo, hi)
+	if err != nil {
+		return nil, err
+	}
+	if ids, ok := p.numbers.FindByRange(loKey, hiKey); ok {
+		return p.contactsByID(ids), nil
+	}
+	return []Contact{}, nil
+}
+
+// FreeNumbers returns the numbers in the block between lo and hi inclusive that
+// do not belong to a contact or a contact in the trash, in ascending order and
+// canonical form. At most limit numbers are returned, and limit must be greater
+// than zero, so a large block is never listed in full. It can be used to
+// allocate new numbers from a block without colliding with existing contacts.
+//
+// Like ListTrash, it locks the phone book for writing, as contacts whose trash
+// retention has expired are deleted first to release their numbers.
+func (p *PhoneBook) FreeNumbers(lo string, hi string, limit int) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("free number limit must be greater than zero")
+	}
+	loKey, hiKey, err := p.numberRange(lo, hi)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers of contacts in the trash are reserved, so request enough numbers
+	// to make up for any that are reserved
+	p.expireTrash()
+	free := []string{}
+	for _, key := range p.numbers.FreeNumbers(loKey, hiKey, limit+len(p.reserved)) {
+		if _, ok := p.reserved[key]; !ok && len(free) < limit {
+			free = append(free, key)
+		}
+	}
+	if canonical, _ := p.policy.Normalize(lo); strings.HasPrefix(canonical, "+") {
+		for i, key := range free {
+			free[i] = "+" + key
+		}
+	}
+	return free, nil
+}
+
+// AddOrderedIndex registers an ordered index of contacts on a named field, which
+// can be queried with FindByRange. The key function returns the value of the
+// field, or false to skip the contact. The index is built from the contacts
+// already in the phone book.
+func (p *PhoneBook) AddOrderedIndex(field string, keyFn func(Contact) (string, bool)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if field == "" {
+		return fmt.Errorf("index name required")
+	}
+	idx := newOrderedIndex(field, keyFn)
+	if err := p.register(idx); err != nil {
+		return err
+	}
+	p.ordered[field] = idx
+	return nil
+}
+
+// numberBound converts a bound of a number range to the key its numbers are
+// stored under.
+func (p *PhoneBook) numberBound(bound string) (string, error) {
+	if bound == "" {
+		return "", nil
+	}
+	prefix, ok := p.policy.NormalizePrefix(bound)
+	if !ok {
+		return "", fmt.Errorf("invalid number range bound %s", bound)
+	}
+	return numberKey(prefix), nil
+}
+
+// numberRange converts the bounds of a number block to the keys its numbers are
+// stored under.
+func (p *PhoneBook) numberRange(lo string, hi string) (string, string, error) {
+	loCanonical, err := p.policy.Normalize(lo)
+	if err != nil {
+		return "", "", err
+	}
+	hiCanonical, err := p.policy.Normalize(hi)
+	if err != nil {
+		return "", "", err
+	}
+	if len(loCanonical) != len(hiCanonical) {
+		return "", "", fmt.Errorf("number range bounds %s and %s must have the same length", loCanonical, hiCanonical)
+	}
+	return numberKey(loCanonical), numberKey(hiCanonical), nil
+}
+
+// orderedIndexName returns the name of the ordered index of a field, which is
+// distinct from the name of any map index of the same field.
+func orderedIndexName(field string) string {
+	return "ordered:" + field
+}
+
+func newOrderedIndex(field string, keyFn func(Contact) (string, bool)) *index.OrderedIndex[*Contact] {
+	return index.NewOrderedIndex(orderedIndexName(field), func(contact *Contact) []string {
+		// Pass a copy so the key function can not modify the stored contact
+		if key, ok := keyFn(contact.clone()); ok {
+			return []string{key}
+		}
+		return nil
+	})
+}
+
+func numberKeys(contact *Contact) []string {
+	keys := make([]string, len(contact.Numbers))
+	for i, number := range contact.Numbers {
+		keys[i] = numberKey(number.Number)
+	}
+	return keys
+}
+
+func postalCodeFromAddress(address string) (string, bool) {
+	if parts := addressParts(address); len(parts) == 5 && parts[3] != "" {
+		return parts[3], true
+	}
+	return "", false
+}
@@ -0,0 +1,175 @@
+package phonebook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_FindByRange(t *testing.T) {
+	phoneBook := New()
+	adams := Contact{Numbers: mobile("0410000100"), FirstName: "Zoe", LastName: "Adams", Address: "1 Foo St, Foo City, Foo State, 2000, Foo Country"}
+	miller := Contact{Numbers: mobile("0410049999"), FirstName: "Amy", LastName: "Miller", Address: "1 Foo St, Foo City, Foo State, 2999, Foo Country"}
+	nash := Contact{Numbers: []PhoneNumber{{Type: Mobile, Number: "0410000000"}, {Type: Work, Number: "0410000001"}}, FirstName: "Bob", LastName: "Nash", Address: "1 Foo St, Foo City, Foo State, 3000, Foo Country"}
+	parker := Contact{Numbers: mobile("0420000000"), FirstName: "Cal", LastName: "Parker"}
+	add(t, phoneBook, &adams)
+	add(t, phoneBook, &miller)
+	add(t, phoneBook, &nash)
+	add(t, phoneBook, &parker)
+
+	tests := []struct {
+		name  string
+		field string
+		lo    string
+		hi    string
+		opts  RangeOptions
+		want  []Contact
+	}{
+		{name: "last names", field: "last_name", lo: "M", hi: "P~", want: []Contact{miller, nash, parker}},
+		{name: "first names", field: "first_name", want: []Contact{miller, nash, parker, adams}},
+		{name: "postal codes", field: "postal_code", lo: "2000", hi: "2999", want: []Contact{adams, miller}},
+		{name: "number block", field: "number", lo: "0410000000", hi: "0410049999", want: []Contact{nash, adams, miller}},
+		{name: "formatted number bounds", field: "number", lo: "0410 000 002", hi: "(0410) 049 999", want: []Contact{adams, miller}},
+		{name: "descending", field: "last_name", opts: RangeOptions{Descending: true}, want: []Contact{parker, nash, miller, adams}},
+		{name: "limit", field: "last_name", lo: "B", opts: RangeOptions{Limit: 2}, want: []Contact{miller, nash}},
+		{name: "no matches", field: "postal_code", lo: "4000", want: []Contact{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := phoneBook.FindByRange(tt.field, tt.lo, tt.hi, tt.opts)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := phoneBook.FindByRange("random", "", "", RangeOptions{})
+	require.EqualError(t, err, "range field random not found")
+	_, err = phoneBook.FindByRange("number", "abc", "", RangeOptions{})
+	require.EqualError(t, err, "invalid number range bound abc")
+}
+
+func TestPhoneBook_FindByRange_irregularAddress(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: "1 St, City, State,2000,AU"}
+	add(t, phoneBook, &contact)
+
+	got, err := phoneBook.FindByRange("postal_code", "2000", "2000", RangeOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []Contact{contact}, got)
+}
+
+func TestPhoneBook_FindByRange_updates(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "Adams"}
+	other := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Baker"}
+	add(t, phoneBook, &contact)
+	add(t, phoneBook, &other)
+
+	updated := contact
+	updated.LastName = "Young"
+	require.NoError(t, phoneBook.UpdateByID(context.Background(), contact.ID, updated))
+	updated.Version = 2
+	got, err := phoneBook.FindByRange("last_name", "", "", RangeOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []Contact{other, updated}, got)
+
+	require.NoError(t, phoneBook.DeleteByID(context.Background(), other.ID))
+	got, err = phoneBook.FindByRange("last_name", "", "", RangeOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []Contact{updated}, got)
+}
+
+func TestPhoneBook_AddOrderedIndex(t *testing.T) {
+	phoneBook := New(WithOrderedIndex("state", stateFromAddress))
+	foo := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: "1 Foo St, Foo City, Foo State, 1111, Foo Country"}
+	bar := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: "1 Bar St, Bar City, Bar State, 2222, Bar Country"}
+	add(t, phoneBook, &foo)
+	add(t, phoneBook, &bar)
+
+	got, err := phoneBook.FindByRange("state", "", "", RangeOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []Contact{bar, foo}, got)
+
+	// Indexes added to a populated phone book are built from existing contacts
+	require.NoError(t, phoneBook.AddOrderedIndex("country", func(contact Contact) (string, bool) {
+		if contact.Address == "" {
+			return "", false
+		}
+		return strings.Split(contact.Address, ", ")[4], true
+	}))
+	got, err = phoneBook.FindByRange("country", "C", "", RangeOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []Contact{foo}, got)
+
+	require.EqualError(t, phoneBook.AddOrderedIndex("state", stateFromAddress), "index ordered:state already exists")
+	require.EqualError(t, phoneBook.AddOrderedIndex("last_name", stateFromAddress), "index ordered:last_name already exists")
+	require.EqualError(t, phoneBook.AddOrderedIndex("", stateFromAddress), "index name required")
+}
+
+func TestWithOrderedIndex_duplicatePanics(t *testing.T) {
+	require.PanicsWithError(t, "index ordered:state already exists", func() {
+		New(WithOrderedIndex("state", stateFromAddress), WithOrderedIndex("state", stateFromAddress))
+	})
+	require.PanicsWithError(t, "index ordered:first_name already exists", func() {
+		New(WithOrderedIndex("first_name", stateFromAddress))
+	})
+}
+
+func TestPhoneBook_FindByNumberRange(t *testing.T) {
+	phoneBook := New()
+	first := Contact{Numbers: []PhoneNumber{{Type: Mobile, Number: "0410049999"}, {Type: Work, Number: "0410000005"}}, FirstName: "One", LastName: "One"}
+	second := Contact{Numbers: mobile("0410000010"), FirstName: "Two", LastName: "Two"}
+	outside := Contact{Numbers: mobile("0410050000"), FirstName: "Three", LastName: "Three"}
+	add(t, phoneBook, &first)
+	add(t, phoneBook, &second)
+	add(t, phoneBook, &outside)
+
+	got, err := phoneBook.FindByNumberRange("0410000000", "0410 049 999")
+	require.NoError(t, err)
+	require.Equal(t, []Contact{first, second}, got)
+
+	got, err = phoneBook.FindByNumberRange("0420000000", "0429999999")
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	_, err = phoneBook.FindByNumberRange("041", "0410049999")
+	require.EqualError(t, err, "phone number must contain 10 digits")
+}
+
+func TestPhoneBook_FreeNumbers(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: []PhoneNumber{{Type: Mobile, Number: "0410000000"}, {Type: Work, Number: "0410000002"}}, FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+
+	free, err := phoneBook.FreeNumbers("0410000000", "0410049999", 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0410000001", "0410000003", "0410000004"}, free)
+
+	// Allocating a free number does not collide with existing contacts
+	_, err = phoneBook.Add(context.Background(), Contact{Numbers: mobile(free[0]), FirstName: "Two", LastName: "Two"})
+	require.NoError(t, err)
+	free, err = phoneBook.FreeNumbers("0410000000", "0410000004", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0410000003", "0410000004"}, free)
+
+	_, err = phoneBook.FreeNumbers("0410000000", "abc", 10)
+	require.EqualError(t, err, "phone number must contain 10 digits")
+	_, err = phoneBook.FreeNumbers("0000000000", "9999999999", 0)
+	require.EqualError(t, err, "free number limit must be greater than zero")
+}
+
+func TestPhoneBook_FreeNumbers_e164(t *testing.T) {
+	phoneBook := New(WithNumberPolicy(E164Policy{DefaultRegion: "AU"}))
+	contact := Contact{Numbers: mobile("0410 000 000"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+
+	free, err := phoneBook.FreeNumbers("0410 000 000", "+61 410 049 999", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"+61410000001", "+61410000002"}, free)
+
+	_, err = phoneBook.FreeNumbers("+61410000000", "+6441000000", 10)
+	require.EqualError(t, err, "number range bounds +61410000000 and +6441000000 must have the same length")
+}
@@ -0,0 +1,190 @@
+package phonebook
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// region holds the numbering plan metadata of a region.
+type region struct {
+	// code is the ISO 3166-1 alpha-2 code of the region.
+	code string
+	// callingCode is the country calling code of the region, without the
+	// leading plus sign.
+	callingCode string
+	// trunkPrefix is the prefix dialled before a national number from within
+	// the region (e.g. 0 in Australia).
+	trunkPrefix string
+	// lengths are the valid lengths of a national significant number, which
+	// excludes the calling code and trunk prefix.
+	lengths []int
+	// formats are the rules used to format numbers of the region for display.
+	// The first matching rule is used.
+	formats []formatRule
+}
+
+// formatRule describes how to display national significant numbers with a given
+// length and leading digits.
+type formatRule struct {
+	// leading are the possible leading digits of numbers the rule applies to.
+	// All numbers match if empty.
+	leading []string
+	length  int
+	// groups are the sizes of each group of digits, which must add up to length.
+	groups []int
+	// national is the template of the national format. $1, $2, etc. are replaced
+	// with each group of digits, and $T with the trunk prefix of the region.
+	national string
+	// international is the template of the international format, excluding the
+	// calling code.
+	international string
+}
+
+// regions holds the metadata of each supported region by region code.
+var regions = map[string]region{
+	"AU": {
+		code: "AU", callingCode: "61", trunkPrefix: "0", lengths: []int{9},
+		formats: []formatRule{
+			{leading: []string{"4", "5"}, length: 9, groups: []int{3, 3, 3}, national: "$T$1 $2 $3", international: "$1 $2 $3"},
+			{leading: []string{"2", "3", "7", "8"}, length: 9, groups: []int{1, 4, 4}, national: "($T$1) $2 $3", international: "$1 $2 $3"},
+		},
+	},
+	"CA": {code: "CA", callingCode: "1", trunkPrefix: "1", lengths: []int{10}, formats: nanpFormats},
+	"DE": {code: "DE", callingCode: "49", trunkPrefix: "0", lengths: []int{7, 8, 9, 10, 11}},
+	"FR": {
+		code: "FR", callingCode: "33", trunkPrefix: "0", lengths: []int{9},
+		formats: []formatRule{
+			{length: 9, groups: []int{1, 2, 2, 2, 2}, national: "$T$1 $2 $3 $4 $5", international: "$1 $2 $3 $4 $5"},
+		},
+	},
+	"GB": {
+		code: "GB", callingCode: "44", trunkPrefix: "0", lengths: []int{9, 10},
+		formats: []formatRule{
+			{leading: []string{"20"}, length: 10, groups: []int{2, 4, 4}, national: "$T$1 $2 $3", international: "$1 $2 $3"},
+			{leading: []string{"7"}, length: 10, groups: []int{4, 6}, national: "$T$1 $2", international: "$1 $2"},
+		},
+	},
+	"IN": {
+		code: "IN", callingCode: "91", trunkPrefix: "0", lengths: []int{10},
+		formats: []formatRule{
+			{length: 10, groups: []int{5, 5}, national: "$T$1 $2", international: "$1 $2"},
+		},
+	},
+	"JP": {code: "JP", callingCode: "81", trunkPrefix: "0", lengths: []int{9, 10}},
+	"NZ": {
+		code: "NZ", callingCode: "64", trunkPrefix: "0", lengths: []int{8, 9, 10},
+		formats: []formatRule{
+			{leading: []string{"2"}, length: 9, groups: []int{2, 3, 4}, national: "$T$1 $2 $3", international: "$1 $2 $3"},
+			{length: 8, groups: []int{1, 3, 4}, national: "$T$1 $2 $3", international: "$1 $2 $3"},
+		},
+	},
+	"SG": {
+		code: "SG", callingCode: "65", lengths: []int{8},
+		formats: []formatRule{
+			{length: 8, groups: []int{4, 4}, national: "$1 $2", international: "$1 $2"},
+		},
+	},
+	"US": {code: "US", callingCode: "1", trunkPrefix: "1", lengths: []int{10}, formats: nanpFormats},
+}
+
+// nanpFormats are the format rules shared by regions of the North American
+// Numbering Plan.
+var nanpFormats = []formatRule{
+	{length: 10, groups: []int{3, 3, 4}, national: "($1) $2-$3", international: "$1-$2-$3"},
+}
+
+// regionsByCallingCode holds the regions sharing each country calling code,
+// sorted by region code.
+var regionsByCallingCode = func() map[string][]region {
+	byCode := map[string][]region{}
+	for _, r := range regions {
+		byCode[r.callingCode] = append(byCode[r.callingCode], r)
+	}
+	for _, rs := range byCode {
+		sort.Slice(rs, func(i, j int) bool { return rs[i].code < rs[j].code })
+	}
+	return byCode
+}()
+
+// maxE164Digits is the maximum number of digits in an E.164 number, including
+// the country calling code.
+const maxE164Digits = 15
+
+// minNationalDigits is the minimum number of digits in the national significant
+// number of a region without metadata.
+const minNationalDigits = 4
+
+// callingCodes holds every country calling code assigned by the ITU, which are
+// used to validate international numbers of regions without metadata. Calling
+// codes are prefix-free, so a number has at most one matching code.
+var callingCodes = func() map[string]bool {
+	assigned := strings.Fields(`
+		1 7
+		20 27 30 31 32 33 34 36 39 40 41 43 44 45 46 47 48 49 51 52 53 54 55 56 57 58
+		60 61 62 63 64 65 66 81 82 84 86 90 91 92 93 94 95 98
+		211 212 213 216 218 220 221 222 223 224 225 226 227 228 229 230 231 232 233 234
+		235 236 237 238 239 240 241 242 243 244 245 246 247 248 249 250 251 252 253 254
+		255 256 257 258 260 261 262 263 264 265 266 267 268 269 290 291 297 298 299
+		350 351 352 353 354 355 356 357 358 359 370 371 372 373 374 375 376 377 378 379
+		380 381 382 383 385 386 387 389 420 421 423 500 501 502 503 504 505 506 507 508
+		509 590 591 592 593 594 595 596 597 598 599 670 672 673 674 675 676 677 678 679
+		680 681 682 683 685 686 687 688 689 690 691 692 800 808 850 852 853 855 856 870
+		878 880 881 882 883 886 888 960 961 962 963 964 965 966 967 968 970 971 972 973
+		974 975 976 977 979 992 993 994 995 996 998`)
+	codes := make(map[string]bool, len(assigned))
+	for _, code := range assigned {
+		codes[code] = true
+	}
+	return codes
+}()
+
+// validLength reports whether the national significant number has a valid
+// length for the region.
+func (r region) validLength(national string) bool {
+	for _, length := range r.lengths {
+		if len(national) == length {
+			return true
+		}
+	}
+	return false
+}
+
+// format returns the national significant number formatted with the rule of the
+// region that matches it, using the national or international template.
+func (r region) format(national string, international bool) (string, bool) {
+	for _, rule := range r.formats {
+		if !rule.matches(national) {
+			continue
+		}
+
+		template := rule.national
+		if international {
+			template = rule.international
+		}
+
+		replacements := []string{"$T", r.trunkPrefix}
+		offset := 0
+		for i, size := range rule.groups {
+			replacements = append(replacements, "$"+strconv.Itoa(i+1), national[offset:offset+size])
+			offset += size
+		}
+		return strings.NewReplacer(replacements...).Replace(template), true
+	}
+	return "", false
+}
+
+func (f formatRule) matches(national string) bool {
+	if len(national) != f.length {
+		return false
+	}
+	if len(f.leading) == 0 {
+		return true
+	}
+	for _, leading := range f.leading {
+		if strings.HasPrefix(national, leading) {
+			return true
+		}
+	}
+	return false
+}
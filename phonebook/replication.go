@@ -0,0 +1,588 @@
+package phonebook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultHeartbeat is the default interval of the heartbeats sent by a primary.
+const defaultHeartbeat = time.Second
+
+// defaultReplicaBuffer is the default number of events buffered for a replica.
+const defaultReplicaBuffer = 1024
+
+// defaultRetry is the default delay before a replica reconnects to its primary.
+const defaultRetry = time.Second
+
+// missedHeartbeats is the number of heartbeats a replica waits for before it
+// considers the connection to its primary lost.
+const missedHeartbeats = 3
+
+// ErrPrimaryClosed is returned by Primary.Serve once the primary is closed.
+var ErrPrimaryClosed = errors.New("primary closed")
+
+// Types of the messages sent by a primary to a replica.
+const (
+	// msgSnapshot holds every contact of the primary, and is followed by the
+	// events after its sequence.
+	msgSnapshot = "snapshot"
+	// msgResume is followed by the events after the sequence the replica
+	// resumed from.
+	msgResume    = "resume"
+	msgEvent     = "event"
+	msgHeartbeat = "heartbeat"
+)
+
+// replicaHello is sent by a replica when it connects to a primary, holding the
+// position in the primary's change feed to resume from.
+type replicaHello struct {
+	Epoch string `json:"epoch,omitempty"`
+	After uint64 `json:"after"`
+}
+
+// replicationMessage is a message sent by a primary to a replica. Messages are
+// sent as lines of JSON.
+type replicationMessage struct {
+	Type string `json:"type"`
+	// Epoch identifies the primary's change feed, so that a replica does not
+	// resume from the sequence of another feed. Set by the first message only.
+	Epoch string `json:"epoch,omitempty"`
+	// Heartbeat is the interval of the primary's heartbeats. Set by the first
+	// message only.
+	Heartbeat time.Duration `json:"heartbeat,omitempty"`
+	// Head is the sequence of the latest event of the primary.
+	Head uint64 `json:"head"`
+	// Sequence is the sequence of the snapshot.
+	Sequence uint64    `json:"sequence,omitempty"`
+	Contacts []Contact `json:"contacts,omitempty"`
+	Event    *Event    `json:"event,omitempty"`
+}
+
+// PrimaryOptions configures a Primary.
+type PrimaryOptions struct {
+	// Heartbeat is how often the primary reports its latest sequence to
+	// replicas, so that idle replicas can report their lag and detect a lost
+	// connection. Defaults to one second.
+	Heartbeat time.Duration
+	// Buffer is the number of events buffered for each replica before it is
+	// disconnected for lagging, after which it reconnects and resumes. Defaults
+	// to 1024.
+	Buffer int
+}
+
+// Primary streams the changes of a phone book to replicas.
+type Primary struct {
+	book *PhoneBook
+	opts PrimaryOptions
+	// epoch identifies the primary, as replicas only resume from the change
+	// feed they were streamed.
+	epoch string
+
+	mu        sync.Mutex
+	closed    bool
+	done      chan struct{}
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	wg        sync.WaitGroup
+}
+
+// NewPrimary returns a new Primary streaming the changes of the phone book.
+func NewPrimary(book *PhoneBook, opts PrimaryOptions) *Primary {
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = defaultHeartbeat
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultReplicaBuffer
+	}
+	return &Primary{
+		book:      book,
+		opts:      opts,
+		epoch:     newID(time.Now()),
+		done:      make(chan struct{}),
+		listeners: map[net.Listener]bool{},
+		conns:     map[net.Conn]bool{},
+	}
+}
+
+// Serve accepts replica connections on the listener, such as a TCP or Unix
+// socket listener, and streams the phone book's changes to each replica. A
+// replica that connects for the first time, or can not resume because the
+// events it missed are no longer retained by the change feed (see
+// WithEventRetention), is sent a snapshot of every contact first. Serve always
+// returns an error, which is ErrPrimaryClosed once the primary is closed.
+func (s *Primary) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = l.Close()
+		return ErrPrimaryClosed
+	}
+	s.listeners[l] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.listeners, l)
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return ErrPrimaryClosed
+			default:
+				return err
+			}
+		}
+		if !s.track(conn) {
+			_ = conn.Close()
+			return ErrPrimaryClosed
+		}
+		go func() {
+			defer s.untrack(conn)
+			s.stream(conn)
+		}()
+	}
+}
+
+// Close stops the listeners passed to Serve and disconnects every replica.
+func (s *Primary) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+
+	var err error
+	for l := range s.listeners {
+		if closeErr := l.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// track adds a replica connection, unless the primary is closed.
+func (s *Primary) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = true
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Primary) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, conn)
+	s.wg.Done()
+}
+
+// stream sends a snapshot or resumes the replica's stream, then sends each event
+// of the change feed until the replica disconnects or lags behind.
+func (s *Primary) stream(conn net.Conn) {
+	defer conn.Close()
+
+	var hello replicaHello
+	if err := json.NewDecoder(conn).Decode(&hello); err != nil {
+		return
+	}
+	first, sub, err := s.start(hello)
+	if err != nil {
+		return
+	}
+	defer sub.Close()
+
+	// Replicas send nothing after their hello, so reading only returns once the
+	// replica disconnects
+	disconnected := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(disconnected)
+	}()
+
+	w := bufio.NewWriter(conn)
+	enc := json.NewEncoder(w)
+	if enc.Encode(first) != nil || w.Flush() != nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.opts.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				// The replica lagged, and resumes once it reconnects
+				return
+			}
+			if enc.Encode(replicationMessage{Type: msgEvent, Head: s.book.Sequence(), Event: &event}) != nil {
+				return
+			}
+			// Buffered events are sent together
+			if len(sub.Events()) == 0 && w.Flush() != nil {
+				return
+			}
+		case <-ticker.C:
+			if enc.Encode(replicationMessage{Type: msgHeartbeat, Head: s.book.Sequence()}) != nil || w.Flush() != nil {
+				return
+			}
+		case <-disconnected:
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// start subscribes to the change feed from the replica's position, or from a
+// snapshot of every contact if the replica can not resume, and returns the first
+// message to send to the replica.
+func (s *Primary) start(hello replicaHello) (replicationMessage, *Subscription, error) {
+	first := replicationMessage{Epoch: s.epoch, Heartbeat: s.opts.Heartbeat}
+	filter := Filter{Buffer: s.opts.Buffer}
+
+	if hello.Epoch == s.epoch && hello.After > 0 {
+		filter.After = hello.After
+		if sub, err := s.book.Subscribe(filter); err == nil {
+			first.Type = msgResume
+			first.Head = s.book.Sequence()
+			return first, sub, nil
+		}
+		filter.After = 0
+	}
+
+	// Changes wait for the read lock, so no change is made between the snapshot
+	// and the subscription
+	s.book.mu.RLock()
+	defer s.book.mu.RUnlock()
+
+	sub, err := s.book.Subscribe(filter)
+	if err != nil {
+		return replicationMessage{}, nil, err
+	}
+	first.Type = msgSnapshot
+	first.Head = s.book.Sequence()
+	first.Sequence = first.Head
+	first.Contacts = make([]Contact, 0, len(s.book.contacts))
+	for _, contact := range s.book.contacts {
+		first.Contacts = append(first.Contacts, contact.clone())
+	}
+	sort.Slice(first.Contacts, func(i, j int) bool { return first.Contacts[i].ID < first.Contacts[j].ID })
+	return first, sub, nil
+}
+
+// ReplicaOptions configures a Replica.
+type ReplicaOptions struct {
+	// Retry is how long the replica waits before reconnecting once the
+	// connection to its primary is lost. Defaults to one second.
+	Retry time.Duration
+}
+
+// ReplicaStatus describes the progress of a replica.
+type ReplicaStatus struct {
+	// Connected reports whether the replica is connected to its primary.
+	Connected bool
+	// Applied is the sequence of the last change of the primary applied by the
+	// replica.
+	Applied uint64
+	// Head is the sequence of the latest change of the primary, as last
+	// reported by the primary.
+	Head uint64
+	// Lag is the number of the primary's changes that the replica has not yet
+	// applied.
+	Lag uint64
+	// LastContact is when the replica last heard from its primary.
+	LastContact time.Time
+	// Err is the error that last disconnected the replica from its primary, if
+	// any.
+	Err error
+}
+
+// Replica keeps a phone book up to date with the changes of a primary phone
+// book, which are streamed by a Primary. The replica's phone book should be
+// created with the same options as the primary's, and should not be changed
+// other than by the replica.
+type Replica struct {
+	book    *PhoneBook
+	network string
+	address string
+	opts    ReplicaOptions
+
+	mu sync.Mutex
+	// epoch identifies the primary the replica was last streamed from.
+	epoch  string
+	status ReplicaStatus
+	// changed is closed and replaced each time the status changes.
+	changed chan struct{}
+}
+
+// NewReplica returns a new Replica that applies the changes of the primary at
+// the address to the phone book. The network is tcp or unix, as accepted by
+// net.Dial.
+func NewReplica(book *PhoneBook, network string, address string, opts ReplicaOptions) *Replica {
+	if opts.Retry <= 0 {
+		opts.Retry = defaultRetry
+	}
+	return &Replica{
+		book:    book,
+		network: network,
+		address: address,
+		opts:    opts,
+		changed: make(chan struct{}),
+	}
+}
+
+// Run connects to the primary and applies its changes, in order, until the
+// context is done. The replica starts from a snapshot of the primary's contacts,
+// which replaces the contents of the phone book. Once the connection is lost,
+// the replica reconnects and resumes from the last change it applied. Changes
+// are recorded in the replica phone book's history and change feed, but are not
+// audited, as they are audited by the primary. Run returns the context's error.
+func (r *Replica) Run(ctx context.Context) error {
+	for {
+		err := r.sync(ctx)
+		r.update(func(status *ReplicaStatus) {
+			status.Connected = false
+			status.Err = err
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.opts.Retry):
+		}
+	}
+}
+
+// Status returns the progress of the replica.
+func (r *Replica) Status() ReplicaStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Wait waits until the replica has applied the primary's changes up to the
+// specified sequence, such as the primary's Sequence after a change, so that
+// the change can be read from the replica.
+func (r *Replica) Wait(ctx context.Context, sequence uint64) error {
+	for {
+		r.mu.Lock()
+		applied, changed := r.status.Applied, r.changed
+		r.mu.Unlock()
+
+		if applied >= sequence {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// sync connects to the primary and applies its messages until the connection is
+// lost.
+func (r *Replica) sync(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, r.network, r.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-stop:
+		}
+	}()
+
+	r.mu.Lock()
+	hello := replicaHello{Epoch: r.epoch, After: r.status.Applied}
+	r.mu.Unlock()
+	if err := json.NewEncoder(conn).Encode(hello); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	var timeout time.Duration
+	for {
+		if timeout > 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+				return err
+			}
+		}
+		var msg replicationMessage
+		if err := dec.Decode(&msg); err != nil {
+			return err
+		}
+		if msg.Heartbeat > 0 {
+			timeout = missedHeartbeats * msg.Heartbeat
+		}
+		if err := r.apply(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// apply applies a message from the primary to the phone book and updates the
+// status.
+func (r *Replica) apply(msg replicationMessage) error {
+	r.mu.Lock()
+	applied := r.status.Applied
+	r.mu.Unlock()
+
+	switch msg.Type {
+	case msgSnapshot:
+		r.book.loadSnapshot(msg.Contacts)
+		applied = msg.Sequence
+	case msgResume, msgHeartbeat:
+	case msgEvent:
+		if msg.Event == nil || msg.Event.Sequence != applied+1 {
+			// Start again from a snapshot rather than miss a change
+			r.mu.Lock()
+			r.epoch = ""
+			r.mu.Unlock()
+			return fmt.Errorf("replication stream skipped from sequence %d", applied)
+		}
+		r.book.replay(*msg.Event)
+		applied = msg.Event.Sequence
+	default:
+		return fmt.Errorf("unknown replication message type '%s'", msg.Type)
+	}
+
+	r.update(func(status *ReplicaStatus) {
+		if msg.Epoch != "" {
+			r.epoch = msg.Epoch
+		}
+		status.Connected = true
+		status.Applied = applied
+		status.Head = msg.Head
+		status.LastContact = time.Now()
+		status.Err = nil
+	})
+	return nil
+}
+
+// update changes the status and wakes anyone waiting for it to change.
+func (r *Replica) update(fn func(status *ReplicaStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fn(&r.status)
+	if r.status.Head < r.status.Applied {
+		r.status.Head = r.status.Applied
+	}
+	r.status.Lag = r.status.Head - r.status.Applied
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// loadSnapshot replaces the contacts of the phone book with the contacts of a
+// primary's snapshot, and publishes the differences.
+func (p *PhoneBook) loadSnapshot(contacts []Contact) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snapshot := make(map[string]*Contact, len(contacts))
+	for i := range contacts {
+		snapshot[contacts[i].ID] = &contacts[i]
+	}
+
+	var changes []change
+	for id, existing := range p.contacts {
+		if updated, ok := snapshot[id]; !ok {
+			changes = append(changes, change{before: existing, permanent: true})
+		} else if !reflect.DeepEqual(*existing, *updated) {
+			changes = append(changes, change{before: existing, after: updated})
+		}
+	}
+	for id, contact := range snapshot {
+		if _, ok := p.contacts[id]; !ok {
+			changes = append(changes, change{after: contact})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changeID(changes[i]) < changeID(changes[j]) })
+
+	// Every contact is removed before any are added, so that a number that moved
+	// between contacts is not removed from its new owner
+	for _, c := range changes {
+		if c.before != nil {
+			p.reindex(c.before, nil)
+		}
+	}
+	for _, c := range changes {
+		if c.after != nil {
+			p.reindex(nil, c.after)
+		}
+	}
+	for _, c := range changes {
+		p.publish(context.Background(), c)
+	}
+}
+
+// replay applies an event of a primary's change feed to the phone book. Deleted
+// contacts are deleted permanently, as the primary's trash is not replicated.
+func (p *PhoneBook) replay(event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := ""
+	if event.Before != nil {
+		id = event.Before.ID
+	}
+	var after *Contact
+	if event.After != nil {
+		id = event.After.ID
+		// The contact was deleted later if its data has been redacted, so it is
+		// deleted straight away
+		if len(event.After.Numbers) > 0 {
+			contact := event.After.clone()
+			after = &contact
+		}
+	}
+
+	before := p.contacts[id]
+	if before == nil && after == nil {
+		return
+	}
+	c := change{before: before, after: after, permanent: true}
+	p.store(c)
+	p.publish(context.Background(), c)
+}
+
+func changeID(c change) string {
+	if c.after != nil {
+		return c.after.ID
+	}
+	return c.before.ID
+}
@@ -0,0 +1,366 @@
+package phonebook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplica(t *testing.T) {
+	tests := []struct {
+		name   string
+		listen func(t *testing.T) net.Listener
+	}{
+		{
+			name: "tcp",
+			listen: func(t *testing.T) net.Listener {
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(t, err)
+				return l
+			},
+		},
+		{
+			name: "unix",
+			listen: func(t *testing.T) net.Listener {
+				// Socket paths are limited in length, so a short temporary directory is used
+				dir, err := os.MkdirTemp("", "pb")
+				require.NoError(t, err)
+				t.Cleanup(func() { _ = os.RemoveAll(dir) })
+				l, err := net.Listen("unix", filepath.Join(dir, "replication.sock"))
+				require.NoError(t, err)
+				return l
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaryBook := New(WithTrash(0))
+			existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+			deleted := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"}
+			add(t, primaryBook, &existing)
+			add(t, primaryBook, &deleted)
+
+			l := newGatedListener(tt.listen(t))
+			primary := NewPrimary(primaryBook, PrimaryOptions{Heartbeat: 10 * time.Millisecond})
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- primary.Serve(l) }()
+
+			// The replica starts from a snapshot, replacing its own contacts
+			replicaBook := New()
+			_, err := replicaBook.Add(context.Background(), Contact{Numbers: mobile("5432167890"), FirstName: "Stale", LastName: "Stale"})
+			require.NoError(t, err)
+			replica := NewReplica(replicaBook, l.Addr().Network(), l.Addr().String(), ReplicaOptions{Retry: 10 * time.Millisecond})
+			ctx, cancel := context.WithCancel(context.Background())
+			runErr := make(chan error, 1)
+			go func() { runErr <- replica.Run(ctx) }()
+
+			conn := l.allow()
+			waitForReplica(t, replica, primaryBook)
+			requireReplicated(t, primaryBook, replicaBook)
+			require.Equal(t, msgSnapshot, conn.firstType(t))
+
+			// Changes are applied in order
+			_, err = primaryBook.Add(context.Background(), Contact{Numbers: mobile("0111111111"), FirstName: "Three", LastName: "Three"})
+			require.NoError(t, err)
+			updated := existing
+			updated.LastName = "Updated"
+			updated.Numbers = mobile("0222222222")
+			require.NoError(t, primaryBook.Update(context.Background(), "0123456789", updated))
+			require.NoError(t, primaryBook.Delete(context.Background(), "9876543210"))
+			require.NoError(t, primaryBook.Tx(context.Background(), func(tx *Tx) error {
+				// The number of an updated contact is reused
+				_, err := tx.Add(Contact{Numbers: mobile("0123456789"), FirstName: "Four", LastName: "Four"})
+				return err
+			}))
+			waitForReplica(t, replica, primaryBook)
+			requireReplicated(t, primaryBook, replicaBook)
+			got, ok := replicaBook.Get("0222222222")
+			require.True(t, ok)
+			require.Equal(t, uint64(2), got.Version)
+			require.Equal(t, []Contact{got}, replicaBook.FindByCity("Foo City"))
+			_, ok = replicaBook.Get("9876543210")
+			require.False(t, ok)
+
+			status := replica.Status()
+			require.True(t, status.Connected)
+			require.Equal(t, primaryBook.Sequence(), status.Applied)
+			require.Equal(t, uint64(0), status.Lag)
+			require.NoError(t, status.Err)
+
+			// Once its connection drops, the replica reconnects to the same
+			// primary and resumes with the changes it missed, without a snapshot
+			require.NoError(t, conn.Close())
+			require.Eventually(t, func() bool { return !replica.Status().Connected }, time.Second, time.Millisecond)
+			require.Error(t, replica.Status().Err)
+			require.NoError(t, primaryBook.Patch(context.Background(), "0111111111", ContactPatch{Address: stringPtr(newAddress("Bar City"))}))
+
+			conn = l.allow()
+			waitForReplica(t, replica, primaryBook)
+			requireReplicated(t, primaryBook, replicaBook)
+			require.Len(t, replicaBook.FindByCity("Bar City"), 1)
+			require.Equal(t, msgResume, conn.firstType(t))
+
+			// A restarted primary has a new change feed, so the replica starts
+			// again from a snapshot
+			require.NoError(t, primary.Close())
+			require.ErrorIs(t, <-serveErr, ErrPrimaryClosed)
+			require.Eventually(t, func() bool { return !replica.Status().Connected }, time.Second, time.Millisecond)
+			require.NoError(t, primaryBook.Delete(context.Background(), "0111111111"))
+
+			inner, err := net.Listen(l.Addr().Network(), l.Addr().String())
+			require.NoError(t, err)
+			l = newGatedListener(inner)
+			primary = NewPrimary(primaryBook, PrimaryOptions{Heartbeat: 10 * time.Millisecond})
+			go func() { serveErr <- primary.Serve(l) }()
+			conn = l.allow()
+			waitForReplica(t, replica, primaryBook)
+			requireReplicated(t, primaryBook, replicaBook)
+			require.Equal(t, msgSnapshot, conn.firstType(t))
+
+			cancel()
+			require.ErrorIs(t, <-runErr, context.Canceled)
+			require.NoError(t, primary.Close())
+			require.ErrorIs(t, <-serveErr, ErrPrimaryClosed)
+		})
+	}
+}
+
+func TestPrimary_start(t *testing.T) {
+	book := New(WithEventRetention(2))
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, book, &contact)
+	primary := NewPrimary(book, PrimaryOptions{})
+
+	tests := []struct {
+		name     string
+		hello    replicaHello
+		wantType string
+	}{
+		{name: "new replica", hello: replicaHello{}, wantType: msgSnapshot},
+		{name: "retained", hello: replicaHello{Epoch: primary.epoch, After: 1}, wantType: msgResume},
+		{name: "other epoch", hello: replicaHello{Epoch: "other", After: 1}, wantType: msgSnapshot},
+		{name: "not reached", hello: replicaHello{Epoch: primary.epoch, After: 5}, wantType: msgSnapshot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, sub, err := primary.start(tt.hello)
+			require.NoError(t, err)
+			defer sub.Close()
+			require.Equal(t, tt.wantType, first.Type)
+			require.Equal(t, primary.epoch, first.Epoch)
+			require.Equal(t, uint64(1), first.Head)
+			if tt.wantType == msgSnapshot {
+				require.Equal(t, uint64(1), first.Sequence)
+				require.Equal(t, []Contact{contact}, first.Contacts)
+			}
+		})
+	}
+
+	// Events that are no longer retained can not be resumed from
+	for _, number := range []string{"0111111111", "0222222222"} {
+		_, err := book.Add(context.Background(), Contact{Numbers: mobile(number), FirstName: "Two", LastName: "Two"})
+		require.NoError(t, err)
+	}
+	first, sub, err := primary.start(replicaHello{Epoch: primary.epoch, After: 1})
+	require.NoError(t, err)
+	sub.Close()
+	require.Equal(t, msgResume, first.Type)
+	first, sub, err = primary.start(replicaHello{Epoch: primary.epoch, After: 0})
+	require.NoError(t, err)
+	sub.Close()
+	require.Equal(t, msgSnapshot, first.Type)
+	require.Len(t, first.Contacts, 3)
+}
+
+func TestReplica_lag(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	book := New()
+	replica := NewReplica(book, "tcp", l.Addr().String(), ReplicaOptions{Retry: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = replica.Run(ctx) }()
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	var hello replicaHello
+	dec := json.NewDecoder(conn)
+	require.NoError(t, dec.Decode(&hello))
+	require.Equal(t, replicaHello{}, hello)
+
+	// A fake primary reports that it is ahead of the snapshot
+	contact := Contact{ID: "1", Version: 1, Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	enc := json.NewEncoder(conn)
+	require.NoError(t, enc.Encode(replicationMessage{Type: msgSnapshot, Epoch: "epoch", Heartbeat: time.Second, Head: 5, Sequence: 2, Contacts: []Contact{contact}}))
+	require.NoError(t, replica.Wait(ctx, 2))
+	status := replica.Status()
+	require.Equal(t, uint64(2), status.Applied)
+	require.Equal(t, uint64(5), status.Head)
+	require.Equal(t, uint64(3), status.Lag)
+	got, ok := book.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, contact, got)
+
+	updated := contact
+	updated.Version = 2
+	updated.LastName = "Updated"
+	require.NoError(t, enc.Encode(replicationMessage{Type: msgEvent, Head: 5, Event: &Event{Sequence: 3, Type: Updated, Before: &contact, After: &updated}}))
+	require.NoError(t, replica.Wait(ctx, 3))
+	require.Equal(t, uint64(2), replica.Status().Lag)
+
+	// A gap in the stream disconnects the replica, which starts again from a
+	// snapshot
+	require.NoError(t, enc.Encode(replicationMessage{Type: msgEvent, Head: 5, Event: &Event{Sequence: 5, Type: Deleted, Before: &updated}}))
+	require.Eventually(t, func() bool { return !replica.Status().Connected }, time.Second, time.Millisecond)
+	require.EqualError(t, replica.Status().Err, "replication stream skipped from sequence 3")
+	_ = conn.Close()
+
+	conn, err = l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, json.NewDecoder(conn).Decode(&hello))
+	require.Equal(t, replicaHello{After: 3}, hello)
+	got, ok = book.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, updated, got)
+}
+
+func TestPhoneBook_replay_redacted(t *testing.T) {
+	book := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, book, &contact)
+
+	// The data of a contact that was later deleted is redacted, so the contact
+	// is deleted straight away
+	book.replay(Event{Sequence: 2, Type: Updated, Before: &Contact{ID: contact.ID}, After: &Contact{ID: contact.ID}})
+	_, ok := book.GetByID(contact.ID)
+	require.False(t, ok)
+	book.replay(Event{Sequence: 3, Type: Deleted, Before: &Contact{ID: contact.ID}})
+
+	other := Contact{ID: "other", Version: 1, Numbers: mobile("0123456789"), FirstName: "Two", LastName: "Two"}
+	book.replay(Event{Sequence: 4, Type: Added, After: &other})
+	got, ok := book.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, other, got)
+}
+
+// waitForReplica waits until the replica has applied every change of the
+// primary.
+func waitForReplica(t *testing.T, replica *Replica, primary *PhoneBook) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, replica.Wait(ctx, primary.Sequence()))
+}
+
+// requireReplicated checks that the replica holds the same contacts as the
+// primary, and finds them by number.
+func requireReplicated(t *testing.T, primary *PhoneBook, replica *PhoneBook) {
+	want := allContacts(primary)
+	require.Equal(t, want, allContacts(replica))
+	for _, contact := range want {
+		for _, number := range contact.NumberStrings() {
+			got, ok := replica.Get(number)
+			require.True(t, ok)
+			require.Equal(t, contact, got)
+		}
+	}
+}
+
+func allContacts(book *PhoneBook) []Contact {
+	book.mu.RLock()
+	defer book.mu.RUnlock()
+
+	contacts := make([]Contact, 0, len(book.contacts))
+	for _, contact := range book.contacts {
+		contacts = append(contacts, contact.clone())
+	}
+	sort.Slice(contacts, func(i, j int) bool { return contacts[i].ID < contacts[j].ID })
+	return contacts
+}
+
+// gatedListener is a listener that holds each accepted connection until the
+// test allows it, and records what the primary writes to it.
+type gatedListener struct {
+	net.Listener
+	accepted chan *recordingConn
+	allowed  chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func newGatedListener(l net.Listener) *gatedListener {
+	return &gatedListener{
+		Listener: l,
+		accepted: make(chan *recordingConn, 1),
+		allowed:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (l *gatedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-l.allowed:
+	case <-l.closed:
+		_ = conn.Close()
+		return nil, net.ErrClosed
+	}
+	recording := &recordingConn{Conn: conn}
+	l.accepted <- recording
+	return recording, nil
+}
+
+func (l *gatedListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// allow lets the next connection be accepted and returns it.
+func (l *gatedListener) allow() *recordingConn {
+	l.allowed <- struct{}{}
+	return <-l.accepted
+}
+
+// recordingConn is a connection that records the data written to it.
+type recordingConn struct {
+	net.Conn
+	mu      sync.Mutex
+	written bytes.Buffer
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	c.written.Write(b)
+	c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+// firstType returns the type of the first message written to the connection.
+func (c *recordingConn) firstType(t *testing.T) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var msg replicationMessage
+	require.NoError(t, json.NewDecoder(bytes.NewReader(c.written.Bytes())).Decode(&msg))
+	return msg.Type
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,163 @@
+package phonebook
+
+import (
+	"context"
+	"sort"
+)
+
+// TagCount is the number of contacts that have a tag.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// FindByTag returns all contacts with the specified tag.
+func (p *PhoneBook) FindByTag(tag string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if found, ok := p.indexes.Get(indexTag, tag); ok {
+		return clones(found)
+	}
+	return []Contact{}
+}
+
+// FindByAllTags returns all contacts that have every one of the specified tags.
+func (p *PhoneBook) FindByAllTags(tags ...string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(tags) == 0 {
+		return []Contact{}
+	}
+
+	// Start with the smallest group to minimise the contacts that are checked
+	tags = append([]string(nil), tags...)
+	sort.Slice(tags, func(i, j int) bool { return p.tags.Count(tags[i]) < p.tags.Count(tags[j]) })
+	found, ok := p.indexes.Get(indexTag, tags[0])
+	if !ok {
+		return []Contact{}
+	}
+
+	matched := []Contact{}
+	for _, contact := range found {
+		hasAll := true
+		for _, tag := range tags[1:] {
+			if !contact.HasTag(tag) {
+				hasAll = false
+				break
+			}
+		}
+		if hasAll {
+			matched = append(matched, contact.clone())
+		}
+	}
+	return matched
+}
+
+// FindByAnyTag returns all contacts that have at least one of the specified tags.
+func (p *PhoneBook) FindByAnyTag(tags ...string) []Contact {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var ids []string
+	for _, tag := range tags {
+		if found, ok := p.indexes.Get(indexTag, tag); ok {
+			for _, contact := range found {
+				ids = append(ids, contact.ID)
+			}
+		}
+	}
+	return p.contactsByID(ids)
+}
+
+// Tags returns each tag in use along with the number of contacts that have it,
+// sorted by tag.
+func (p *PhoneBook) Tags() []TagCount {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	counts := []TagCount{}
+	for _, tag := range p.tags.Keys() {
+		counts = append(counts, TagCount{Tag: tag, Count: p.tags.Count(tag)})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Tag < counts[j].Tag })
+	return counts
+}
+
+// RenameTag renames a tag across all contacts that have it, and returns the
+// number of contacts that were changed. Contacts that already have the new tag
+// keep a single copy of it. Either every contact is changed or none are.
+func (p *PhoneBook) RenameTag(ctx context.Context, oldTag string, newTag string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := validateTag(newTag); err != nil {
+		return 0, err
+	}
+
+	return p.retag(ctx, oldTag, func(tags []string) []string {
+		renamed := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if tag == oldTag {
+				tag = newTag
+			}
+			if !contains(renamed, tag) {
+				renamed = append(renamed, tag)
+			}
+		}
+		return renamed
+	})
+}
+
+// RemoveTag removes a tag from all contacts that have it, and returns the number
+// of contacts that were changed. Either every contact is changed or none are.
+func (p *PhoneBook) RemoveTag(ctx context.Context, tag string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.retag(ctx, tag, func(tags []string) []string {
+		removed := make([]string, 0, len(tags))
+		for _, t := range tags {
+			if t != tag {
+				removed = append(removed, t)
+			}
+		}
+		return removed
+	})
+}
+
+// retag replaces the tags of each contact with the specified tag with the tags
+// returned by fn. Either every contact is changed or none are.
+func (p *PhoneBook) retag(ctx context.Context, tag string, fn func(tags []string) []string) (int, error) {
+	found, ok := p.indexes.Get(indexTag, tag)
+	if !ok {
+		return 0, nil
+	}
+
+	tx := &Tx{p: p, ctx: ctx}
+	err := p.atomically(tx, func() error {
+		for _, existing := range found {
+			updated := existing.clone()
+			updated.Tags = fn(updated.Tags)
+			updated.Version++
+			if err := p.replace(ctx, existing, &updated); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(found), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
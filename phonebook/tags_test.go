@@ -0,0 +1,123 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_FindByTag(t *testing.T) {
+	phoneBook := New()
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers", "on-call"}}
+	want2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Tags: []string{"suppliers"}}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Tags: []string{"on-call"}}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
+
+	require.ElementsMatch(t, []Contact{want1, want2}, phoneBook.FindByTag("suppliers"))
+	require.Empty(t, phoneBook.FindByTag("random"))
+
+	require.NoError(t, phoneBook.Delete(context.Background(), want1.Numbers[0].Number))
+	require.Equal(t, []Contact{want2}, phoneBook.FindByTag("suppliers"))
+	require.Equal(t, []Contact{dummy}, phoneBook.FindByTag("on-call"))
+}
+
+func TestPhoneBook_FindByAllTags(t *testing.T) {
+	phoneBook := New()
+	want := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers", "on-call", "sydney"}}
+	dummy1 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Tags: []string{"suppliers"}}
+	dummy2 := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Tags: []string{"on-call"}}
+	add(t, phoneBook, &want)
+	add(t, phoneBook, &dummy1)
+	add(t, phoneBook, &dummy2)
+
+	tags := []string{"suppliers", "on-call"}
+	require.Equal(t, []Contact{want}, phoneBook.FindByAllTags(tags...))
+	require.Equal(t, []string{"suppliers", "on-call"}, tags)
+	require.Empty(t, phoneBook.FindByAllTags("suppliers", "random"))
+	require.Empty(t, phoneBook.FindByAllTags())
+}
+
+func TestPhoneBook_FindByAnyTag(t *testing.T) {
+	phoneBook := New()
+	want1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers", "on-call"}}
+	want2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Tags: []string{"sydney"}}
+	dummy := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Tags: []string{"melbourne"}}
+	add(t, phoneBook, &want1)
+	add(t, phoneBook, &want2)
+	add(t, phoneBook, &dummy)
+
+	require.ElementsMatch(t, []Contact{want1, want2}, phoneBook.FindByAnyTag("suppliers", "on-call", "sydney"))
+	require.Empty(t, phoneBook.FindByAnyTag("random"))
+}
+
+func TestPhoneBook_Tags(t *testing.T) {
+	phoneBook := New()
+	add(t, phoneBook, &Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers", "on-call"}})
+	add(t, phoneBook, &Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Tags: []string{"suppliers"}})
+	add(t, phoneBook, &Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three"})
+
+	require.Equal(t, []TagCount{{Tag: "on-call", Count: 1}, {Tag: "suppliers", Count: 2}}, phoneBook.Tags())
+}
+
+func TestPhoneBook_RenameTag(t *testing.T) {
+	phoneBook := New()
+	contact1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers", "vendors"}}
+	contact2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Tags: []string{"on-call", "suppliers"}}
+	add(t, phoneBook, &contact1)
+	add(t, phoneBook, &contact2)
+
+	changed, err := phoneBook.RenameTag(context.Background(), "suppliers", "vendors")
+	require.NoError(t, err)
+	require.Equal(t, 2, changed)
+
+	contact1.Tags = []string{"vendors"}
+	contact2.Tags = []string{"on-call", "vendors"}
+	contact1.Version, contact2.Version = 2, 2
+	require.Empty(t, phoneBook.FindByTag("suppliers"))
+	require.ElementsMatch(t, []Contact{contact1, contact2}, phoneBook.FindByTag("vendors"))
+	require.Equal(t, []TagCount{{Tag: "on-call", Count: 1}, {Tag: "vendors", Count: 2}}, phoneBook.Tags())
+
+	_, err = phoneBook.RenameTag(context.Background(), "vendors", " ")
+	require.EqualError(t, err, "tag must not be empty")
+}
+
+func TestPhoneBook_RenameTag_auditError(t *testing.T) {
+	phoneBook := New(WithAuditSink(&limitedSink{remaining: 3}))
+	contact1 := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers"}}
+	contact2 := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Tags: []string{"suppliers"}}
+	add(t, phoneBook, &contact1)
+	add(t, phoneBook, &contact2)
+	sequence := phoneBook.Sequence()
+
+	// The first contact's change is audited but not the second's, so neither
+	// contact is changed
+	changed, err := phoneBook.RenameTag(context.Background(), "suppliers", "vendors")
+	require.EqualError(t, err, "failed to write audit record: sink unavailable")
+	require.Equal(t, 0, changed)
+	require.ElementsMatch(t, []Contact{contact1, contact2}, phoneBook.FindByTag("suppliers"))
+	require.Empty(t, phoneBook.FindByTag("vendors"))
+	require.Equal(t, sequence, phoneBook.Sequence())
+}
+
+func TestPhoneBook_RemoveTag(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers", "on-call"}}
+	add(t, phoneBook, &contact)
+
+	changed, err := phoneBook.RemoveTag(context.Background(), "suppliers")
+	require.NoError(t, err)
+	require.Equal(t, 1, changed)
+	changed, err = phoneBook.RemoveTag(context.Background(), "random")
+	require.NoError(t, err)
+	require.Equal(t, 0, changed)
+
+	contact.Tags = []string{"on-call"}
+	contact.Version = 2
+	got, ok := phoneBook.Get(contact.Numbers[0].Number)
+	require.True(t, ok)
+	require.Equal(t, contact, got)
+	require.Empty(t, phoneBook.FindByTag("suppliers"))
+}
@@ -0,0 +1,149 @@
+package phonebook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TrashedContact is a deleted contact held in the trash.
+type TrashedContact struct {
+	Contact   Contact
+	DeletedAt time.Time
+}
+
+// Restore moves the contact in the trash that owns the specified number back
+// into the phone book. It fails with a ConstraintError if the contact now
+// violates a unique index.
+func (p *PhoneBook) Restore(ctx context.Context, number string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expireTrash()
+
+	trashed, ok := p.trashedByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found in trash for number %s", number)
+	}
+
+	contact := trashed.Contact.clone()
+	contact.Version++
+	if err := p.checkConstraints(nil, &contact); err != nil {
+		return err
+	}
+
+	if err := p.insert(ctx, &contact); err != nil {
+		return err
+	}
+	p.release(&contact)
+	return nil
+}
+
+// ListTrash returns the contacts in the trash, most recently deleted first.
+func (p *PhoneBook) ListTrash() []TrashedContact {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expireTrash()
+
+	trashed := make([]TrashedContact, 0, len(p.trash))
+	for _, t := range p.trash {
+		t.Contact = t.Contact.clone()
+		trashed = append(trashed, t)
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		if trashed[i].DeletedAt.Equal(trashed[j].DeletedAt) {
+			return trashed[i].Contact.ID > trashed[j].Contact.ID
+		}
+		return trashed[i].DeletedAt.After(trashed[j].DeletedAt)
+	})
+	return trashed
+}
+
+// Purge permanently deletes the contact in the trash that owns the specified
+// number, along with its history, and releases its numbers.
+func (p *PhoneBook) Purge(ctx context.Context, number string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expireTrash()
+
+	trashed, ok := p.trashedByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found in trash for number %s", number)
+	}
+	return p.purge(ctx, &trashed.Contact)
+}
+
+// EmptyTrash permanently deletes every contact in the trash and returns the
+// number of contacts deleted.
+func (p *PhoneBook) EmptyTrash(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := 0
+	for _, trashed := range p.trash {
+		if err := p.purge(ctx, &trashed.Contact); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// discard moves a contact that has been removed from every index to the trash,
+// and reserves its numbers.
+func (p *PhoneBook) discard(contact *Contact) {
+	p.trash[contact.ID] = TrashedContact{Contact: contact.clone(), DeletedAt: time.Now()}
+	for _, number := range contact.NumberStrings() {
+		p.reserved[numberKey(number)] = contact.ID
+	}
+}
+
+// release removes the contact from the trash and releases its numbers.
+func (p *PhoneBook) release(contact *Contact) {
+	for _, number := range contact.NumberStrings() {
+		delete(p.reserved, numberKey(number))
+	}
+	delete(p.trash, contact.ID)
+}
+
+// purge permanently deletes the contact in the trash, so that no data remains.
+// Nothing is modified if the purge can not be audited.
+func (p *PhoneBook) purge(ctx context.Context, contact *Contact) error {
+	if err := p.audit(newAuditRecord(ctx, AuditPurged, contact, nil)); err != nil {
+		return err
+	}
+	p.release(contact)
+	delete(p.history, contact.ID)
+	p.feed.redact(contact.ID)
+	return nil
+}
+
+// expireTrash purges the contacts that have been in the trash for longer than
+// its retention, which are audited without an actor. Contacts that can not be
+// audited are purged by a later call. Nothing is purged within a transaction, as
+// a purge can not be rolled back.
+func (p *PhoneBook) expireTrash() {
+	if p.trashRetention <= 0 || p.tx != nil {
+		return
+	}
+	cutoff := time.Now().Add(-p.trashRetention)
+	for _, trashed := range p.trash {
+		if trashed.DeletedAt.Before(cutoff) {
+			_ = p.purge(context.Background(), &trashed.Contact)
+		}
+	}
+}
+
+func (p *PhoneBook) trashedByNumber(number string) (TrashedContact, bool) {
+	canonical, err := p.policy.Normalize(number)
+	if err != nil {
+		return TrashedContact{}, false
+	}
+	if id, ok := p.reserved[numberKey(canonical)]; ok {
+		return p.trash[id], true
+	}
+	return TrashedContact{}, false
+}
@@ -0,0 +1,153 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_trash(t *testing.T) {
+	phoneBook := New(WithTrash(0))
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	add(t, phoneBook, &contact)
+
+	require.NoError(t, phoneBook.Delete(context.Background(), "0123456789"))
+	_, ok := phoneBook.Get("0123456789")
+	require.False(t, ok)
+	require.Empty(t, phoneBook.FindByCity("Foo City"))
+	require.Empty(t, phoneBook.FindByPrefix("0123"))
+
+	trashed := phoneBook.ListTrash()
+	require.Len(t, trashed, 1)
+	require.Equal(t, contact, trashed[0].Contact)
+	require.False(t, trashed[0].DeletedAt.IsZero())
+
+	// The number stays reserved while the contact is in the trash
+	_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile("0123456789"), FirstName: "Two", LastName: "Two"})
+	require.EqualError(t, err, "number already exists: 0123456789")
+	free, err := phoneBook.FreeNumbers("0123456788", "0123456790", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0123456788", "0123456790"}, free)
+
+	require.NoError(t, phoneBook.Restore(context.Background(), "0123456789"))
+	restored := contact
+	restored.Version = 2
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, restored, got)
+	require.Equal(t, []Contact{restored}, phoneBook.FindByCity("Foo City"))
+	require.Empty(t, phoneBook.ListTrash())
+
+	// History is kept through the trash
+	history, err := phoneBook.History("0123456789")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, []EventType{Added, Deleted, Added}, []EventType{history[0].Type, history[1].Type, history[2].Type})
+	require.Equal(t, contact, history[1].Contact)
+
+	require.EqualError(t, phoneBook.Restore(context.Background(), "0123456789"), "contact not found in trash for number 0123456789")
+}
+
+func TestPhoneBook_Restore_constraintError(t *testing.T) {
+	phoneBook := New(WithTrash(0), WithUniqueEmails())
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Emails: []Email{{Type: Personal, Address: "foo@example.com"}}}
+	add(t, phoneBook, &contact)
+	require.NoError(t, phoneBook.Delete(context.Background(), "0123456789"))
+
+	other := Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Emails: contact.Emails}
+	add(t, phoneBook, &other)
+
+	var constraintErr *ConstraintError
+	require.ErrorAs(t, phoneBook.Restore(context.Background(), "0123456789"), &constraintErr)
+	require.Len(t, phoneBook.ListTrash(), 1)
+}
+
+func TestPhoneBook_Purge(t *testing.T) {
+	phoneBook := New(WithTrash(0))
+	sub, err := phoneBook.Subscribe(Filter{})
+	require.NoError(t, err)
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+	require.NoError(t, phoneBook.Delete(context.Background(), "0123456789"))
+
+	require.NoError(t, phoneBook.Purge(context.Background(), "0123 456 789"))
+
+	// No data remains once a contact is purged
+	require.Empty(t, phoneBook.ListTrash())
+	require.Empty(t, phoneBook.trash)
+	require.Empty(t, phoneBook.reserved)
+	require.Empty(t, phoneBook.history)
+	for _, event := range phoneBook.feed.retained {
+		for _, c := range []*Contact{event.Before, event.After} {
+			if c != nil {
+				require.Equal(t, &Contact{ID: contact.ID}, c)
+			}
+		}
+	}
+	require.Equal(t, Added, (<-sub.Events()).Type)
+	require.Equal(t, Deleted, (<-sub.Events()).Type)
+	sub.Close()
+
+	// The number can be reused
+	_, err = phoneBook.Add(context.Background(), Contact{Numbers: mobile("0123456789"), FirstName: "Two", LastName: "Two"})
+	require.NoError(t, err)
+
+	require.EqualError(t, phoneBook.Purge(context.Background(), "0123456789"), "contact not found in trash for number 0123456789")
+}
+
+func TestPhoneBook_EmptyTrash(t *testing.T) {
+	phoneBook := New(WithTrash(0))
+	for _, number := range []string{"0123456789", "9876543210"} {
+		_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile(number), FirstName: "Foo", LastName: "Bar"})
+		require.NoError(t, err)
+		require.NoError(t, phoneBook.Delete(context.Background(), number))
+		time.Sleep(time.Millisecond)
+	}
+
+	trashed := phoneBook.ListTrash()
+	require.Len(t, trashed, 2)
+	require.Equal(t, "9876543210", trashed[0].Contact.Numbers[0].Number)
+
+	purged, err := phoneBook.EmptyTrash(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	require.Empty(t, phoneBook.ListTrash())
+	require.Empty(t, phoneBook.reserved)
+	require.Empty(t, phoneBook.history)
+	purged, err = phoneBook.EmptyTrash(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, purged)
+}
+
+func TestPhoneBook_trashRetention(t *testing.T) {
+	phoneBook := New(WithTrash(time.Hour))
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+	require.NoError(t, phoneBook.Delete(context.Background(), "0123456789"))
+	require.Len(t, phoneBook.ListTrash(), 1)
+
+	// Contacts are purged once they have been in the trash for the retention
+	trashed := phoneBook.trash[contact.ID]
+	trashed.DeletedAt = time.Now().Add(-2 * time.Hour)
+	phoneBook.trash[contact.ID] = trashed
+	require.Empty(t, phoneBook.ListTrash())
+	require.Empty(t, phoneBook.history)
+	_, err := phoneBook.Add(context.Background(), Contact{Numbers: mobile("0123456789"), FirstName: "Two", LastName: "Two"})
+	require.NoError(t, err)
+}
+
+func TestPhoneBook_Delete_withoutTrash(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+	require.NoError(t, phoneBook.Delete(context.Background(), "0123456789"))
+
+	require.Empty(t, phoneBook.ListTrash())
+	require.Empty(t, phoneBook.history)
+	for _, event := range phoneBook.feed.retained {
+		require.True(t, event.Before == nil || event.Before.FirstName == "")
+		require.True(t, event.After == nil || event.After.FirstName == "")
+	}
+}
@@ -0,0 +1,158 @@
+package phonebook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Tx is a transaction, which applies a group of changes to a phone book
+// atomically. A Tx is only valid within the function passed to PhoneBook.Tx.
+type Tx struct {
+	p   *PhoneBook
+	ctx context.Context
+	// changes holds the changes stored by the transaction, in order.
+	changes []change
+	done    bool
+}
+
+// Tx runs fn within a transaction, and commits the changes it makes once fn
+// returns, so that either every change is applied or none are. The transaction
+// reads its own changes as they are made, but other readers wait until the
+// transaction has finished, so they never see part of it. The transaction is
+// rolled back if fn returns an error or panics, or if its changes can not be
+// audited, and the error is returned.
+//
+// The changes are audited, recorded in each contact's history and published to
+// the change feed in order when the transaction commits, using the actor of the
+// context. fn must only use the phone book through tx, as calling the phone
+// book's methods would wait for the transaction to finish.
+func (p *PhoneBook) Tx(ctx context.Context, fn func(tx *Tx) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tx := &Tx{p: p, ctx: ctx}
+	return p.atomically(tx, func() error { return fn(tx) })
+}
+
+// Add adds a contact within the transaction and returns the ID assigned to it,
+// as PhoneBook.Add does. Nothing is modified if an error is returned, and the
+// transaction can continue.
+func (tx *Tx) Add(contact Contact) (string, error) {
+	if tx.done {
+		return "", errTxDone
+	}
+	return tx.p.add(tx.ctx, contact)
+}
+
+// Update updates the contact that owns the specified number within the
+// transaction, as PhoneBook.Update does.
+func (tx *Tx) Update(number string, update Contact) error {
+	if tx.done {
+		return errTxDone
+	}
+	existing, ok := tx.p.contactByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found for number %s", number)
+	}
+	return tx.p.update(tx.ctx, existing, update)
+}
+
+// Delete deletes the contact that owns the specified number within the
+// transaction, as PhoneBook.Delete does.
+func (tx *Tx) Delete(number string) error {
+	if tx.done {
+		return errTxDone
+	}
+	if contact, ok := tx.p.contactByNumber(number); ok {
+		return tx.p.remove(tx.ctx, contact)
+	}
+	return nil
+}
+
+// Upsert adds or replaces the contact within the transaction, as
+// PhoneBook.Upsert does.
+func (tx *Tx) Upsert(contact Contact) (string, bool, error) {
+	if tx.done {
+		return "", false, errTxDone
+	}
+	result := tx.p.upsert(tx.ctx, contact)
+	return result.ID, result.Replaced, result.Err
+}
+
+// Patch changes the fields of the contact that owns the specified number within
+// the transaction, as PhoneBook.Patch does.
+func (tx *Tx) Patch(number string, patch ContactPatch) error {
+	if tx.done {
+		return errTxDone
+	}
+	existing, ok := tx.p.contactByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found for number %s", number)
+	}
+	return tx.p.update(tx.ctx, existing, patch.apply(existing.clone()))
+}
+
+// Get returns the contact that owns the specified number, including the changes
+// made by the transaction.
+func (tx *Tx) Get(number string) (Contact, bool) {
+	if tx.done {
+		return Contact{}, false
+	}
+	if contact, ok := tx.p.contactByNumber(number); ok {
+		return contact.clone(), true
+	}
+	return Contact{}, false
+}
+
+var errTxDone = errors.New("transaction has already finished")
+
+// atomically runs fn within the transaction, and commits the changes made once
+// fn returns. The changes are rolled back if fn returns an error or panics. The
+// phone book must be locked.
+func (p *PhoneBook) atomically(tx *Tx, fn func() error) error {
+	p.expireTrash()
+	p.tx = tx
+	defer func() {
+		p.tx = nil
+		tx.done = true
+		if r := recover(); r != nil {
+			p.rollback(tx)
+			panic(r)
+		}
+	}()
+
+	if err := fn(); err != nil {
+		p.rollback(tx)
+		return err
+	}
+	return p.commit(tx.ctx, tx)
+}
+
+// commit audits the transaction's changes together, then records and publishes
+// each of them. The transaction is rolled back if its changes can not be
+// audited.
+func (p *PhoneBook) commit(ctx context.Context, tx *Tx) error {
+	records := make([]AuditRecord, len(tx.changes))
+	for i, c := range tx.changes {
+		records[i] = c.auditRecord(ctx)
+	}
+	if err := p.audit(records...); err != nil {
+		p.rollback(tx)
+		return err
+	}
+
+	for _, c := range tx.changes {
+		p.publish(ctx, c)
+	}
+	tx.changes = nil
+	return nil
+}
+
+// rollback undoes the transaction's stored changes in reverse order.
+func (p *PhoneBook) rollback(tx *Tx) {
+	for i := len(tx.changes) - 1; i >= 0; i-- {
+		p.undo(tx.changes[i])
+	}
+	tx.changes = nil
+}
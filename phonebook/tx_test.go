@@ -0,0 +1,241 @@
+package phonebook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_Tx(t *testing.T) {
+	var log bytes.Buffer
+	phoneBook := New(WithAuditSink(NewJSONAuditSink(&log)))
+	existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+	add(t, phoneBook, &existing)
+	sub, err := phoneBook.Subscribe(Filter{})
+	require.NoError(t, err)
+
+	ctx := ContextWithActor(context.Background(), "alice")
+	var id string
+	err = phoneBook.Tx(ctx, func(tx *Tx) error {
+		var err error
+		id, err = tx.Add(Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: newAddress("Foo City")})
+		if err != nil {
+			return err
+		}
+
+		// The transaction reads its own changes
+		added, ok := tx.Get("9876543210")
+		require.True(t, ok)
+		require.Equal(t, id, added.ID)
+
+		updated := existing
+		updated.LastName = "Updated"
+		if err := tx.Update("0123456789", updated); err != nil {
+			return err
+		}
+		got, ok := tx.Get("0123456789")
+		require.True(t, ok)
+		require.Equal(t, "Updated", got.LastName)
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, ok := phoneBook.Get("9876543210")
+	require.True(t, ok)
+	require.Equal(t, id, got.ID)
+	require.Len(t, phoneBook.FindByCity("Foo City"), 2)
+	require.Equal(t, []Contact{got}, phoneBook.FindByName("Two", "Two"))
+
+	// The changes are published and audited in order once committed
+	require.Equal(t, Added, (<-sub.Events()).Type)
+	require.Equal(t, Updated, (<-sub.Events()).Type)
+	records := auditRecords(t, log.String())
+	require.Len(t, records, 3)
+	require.Equal(t, AuditAdded, records[1].Action)
+	require.Equal(t, AuditUpdated, records[2].Action)
+	require.Equal(t, "alice", records[2].Actor)
+	history, err := phoneBook.History("0123456789")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, "alice", history[1].Actor)
+}
+
+func TestPhoneBook_Tx_rollback(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		fail    func() error
+		wantErr string
+	}{
+		{
+			name:    "error",
+			fail:    func() error { return errors.New("failed") },
+			wantErr: "failed",
+		},
+		{
+			name:    "error with trash",
+			opts:    []Option{WithTrash(0)},
+			fail:    func() error { return errors.New("failed") },
+			wantErr: "failed",
+		},
+		{
+			name:    "audit error",
+			opts:    []Option{WithAuditSink(&limitedSink{remaining: 2})},
+			fail:    func() error { return nil },
+			wantErr: "failed to write audit record: sink unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phoneBook := New(tt.opts...)
+			existing := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Address: newAddress("Foo City")}
+			deleted := Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three", Address: newAddress("Bar City")}
+			add(t, phoneBook, &existing)
+			add(t, phoneBook, &deleted)
+			sequence := phoneBook.Sequence()
+
+			err := phoneBook.Tx(context.Background(), func(tx *Tx) error {
+				if _, err := tx.Add(Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two", Address: newAddress("Foo City")}); err != nil {
+					return err
+				}
+				updated := existing
+				updated.Numbers = mobile("1111111111")
+				updated.Address = newAddress("Bar City")
+				if err := tx.Update("0123456789", updated); err != nil {
+					return err
+				}
+				if err := tx.Delete("5432167890"); err != nil {
+					return err
+				}
+				return tt.fail()
+			})
+			require.EqualError(t, err, tt.wantErr)
+
+			// None of the changes remain in the phone book or any index
+			_, ok := phoneBook.Get("9876543210")
+			require.False(t, ok)
+			_, ok = phoneBook.Get("1111111111")
+			require.False(t, ok)
+			got, ok := phoneBook.Get("0123456789")
+			require.True(t, ok)
+			require.Equal(t, existing, got)
+			got, ok = phoneBook.Get("5432167890")
+			require.True(t, ok)
+			require.Equal(t, deleted, got)
+			require.Equal(t, []Contact{existing}, phoneBook.FindByCity("Foo City"))
+			require.Equal(t, []Contact{deleted}, phoneBook.FindByCity("Bar City"))
+			require.Empty(t, phoneBook.FindByName("Two", ""))
+			require.Empty(t, phoneBook.FindByKeypad("896"))
+			require.Empty(t, phoneBook.FindByNumberSuffix("3210"))
+			found, err := phoneBook.FindByNumberRange("0000000000", "9999999999")
+			require.NoError(t, err)
+			require.Equal(t, []Contact{existing, deleted}, found)
+			require.Empty(t, phoneBook.ListTrash())
+			require.Equal(t, sequence, phoneBook.Sequence())
+			for _, c := range []Contact{existing, deleted} {
+				history, err := phoneBook.HistoryByID(c.ID)
+				require.NoError(t, err)
+				require.Len(t, history, 1)
+			}
+		})
+	}
+}
+
+func TestPhoneBook_Tx_panic(t *testing.T) {
+	phoneBook := New()
+	require.PanicsWithValue(t, "failed", func() {
+		_ = phoneBook.Tx(context.Background(), func(tx *Tx) error {
+			_, err := tx.Add(Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"})
+			require.NoError(t, err)
+			panic("failed")
+		})
+	})
+
+	_, ok := phoneBook.Get("0123456789")
+	require.False(t, ok)
+	// The phone book is not left locked
+	add(t, phoneBook, &Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"})
+}
+
+func TestPhoneBook_Tx_operationError(t *testing.T) {
+	phoneBook := New()
+	add(t, phoneBook, &Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"})
+
+	var finished *Tx
+	err := phoneBook.Tx(context.Background(), func(tx *Tx) error {
+		finished = tx
+		// A failed operation modifies nothing, and the transaction can continue
+		_, err := tx.Add(Contact{Numbers: mobile("0123456789"), FirstName: "Two", LastName: "Two"})
+		require.EqualError(t, err, "number already exists: 0123456789")
+		require.EqualError(t, tx.Update("9876543210", Contact{}), "contact not found for number 9876543210")
+
+		// Numbers added earlier in the transaction are taken
+		_, err = tx.Add(Contact{Numbers: mobile("9876543210"), FirstName: "Two", LastName: "Two"})
+		require.NoError(t, err)
+		_, err = tx.Add(Contact{Numbers: mobile("9876543210"), FirstName: "Three", LastName: "Three"})
+		require.EqualError(t, err, "number already exists: 9876543210")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, phoneBook.FindByPrefix(""), 2)
+
+	_, err = finished.Add(Contact{Numbers: mobile("5432167890"), FirstName: "Three", LastName: "Three"})
+	require.EqualError(t, err, "transaction has already finished")
+	_, ok := finished.Get("0123456789")
+	require.False(t, ok)
+}
+
+func TestPhoneBook_Tx_isolation(t *testing.T) {
+	phoneBook := New()
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = phoneBook.Tx(context.Background(), func(tx *Tx) error {
+			for i := 0; i < 30; i++ {
+				if _, err := tx.Add(Contact{Numbers: mobile(fmt.Sprintf("01234567%02d", i)), FirstName: "Foo", LastName: "Bar"}); err != nil {
+					return err
+				}
+				if i == 0 {
+					close(started)
+					<-proceed
+				}
+			}
+			return nil
+		})
+	}()
+
+	<-started
+	read := make(chan int)
+	go func() { read <- len(phoneBook.FindByName("Foo", "Bar")) }()
+	close(proceed)
+
+	// Readers wait for the transaction, so they see all of it or none of it
+	count := <-read
+	require.Contains(t, []int{0, 30}, count)
+	wg.Wait()
+	require.Len(t, phoneBook.FindByName("Foo", "Bar"), 30)
+}
+
+// limitedSink is an AuditSink that fails once it has written a number of
+// records.
+type limitedSink struct {
+	remaining int
+}
+
+func (s *limitedSink) Write(AuditRecord) error {
+	if s.remaining == 0 {
+		return errors.New("sink unavailable")
+	}
+	s.remaining--
+	return nil
+}
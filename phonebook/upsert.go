@@ -0,0 +1,92 @@
+package phonebook
+
+import (
+	"context"
+	"fmt"
+)
+
+// ContactPatch holds the fields of a contact to change with Patch. Nil fields
+// are left unchanged, while a pointer to an empty slice clears a field.
+type ContactPatch struct {
+	Numbers   *[]PhoneNumber
+	FirstName *string
+	LastName  *string
+	Address   *string
+	Emails    *[]Email
+	Websites  *[]Website
+	Messaging *[]MessagingHandle
+	Tags      *[]string
+	// Custom holds the custom fields to set by name. A nil value removes the
+	// field, and fields that are not in the map are left unchanged.
+	Custom map[string]*string
+}
+
+// Upsert adds the contact, or replaces the existing contact that owns any of its
+// numbers, and returns the contact's ID and whether an existing contact was
+// replaced. A contact with an ID replaces the contact with that ID instead, and
+// fails if there is no such contact. The contact is checked as by Add or Update,
+// and fails with the same errors.
+func (p *PhoneBook) Upsert(ctx context.Context, contact Contact) (string, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expireTrash()
+	result := p.upsert(ctx, contact)
+	return result.ID, result.Replaced, result.Err
+}
+
+// Patch changes the fields of the contact that owns the specified number that
+// are set in the patch, and leaves the other fields unchanged. The patched
+// contact is checked as by Update.
+func (p *PhoneBook) Patch(ctx context.Context, number string, patch ContactPatch) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, ok := p.contactByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found for number %s", number)
+	}
+	return p.update(ctx, existing, patch.apply(existing.clone()))
+}
+
+// apply returns the contact with the fields of the patch applied.
+func (patch ContactPatch) apply(contact Contact) Contact {
+	if patch.Numbers != nil {
+		contact.Numbers = append([]PhoneNumber(nil), *patch.Numbers...)
+	}
+	if patch.FirstName != nil {
+		contact.FirstName = *patch.FirstName
+	}
+	if patch.LastName != nil {
+		contact.LastName = *patch.LastName
+	}
+	if patch.Address != nil {
+		contact.Address = *patch.Address
+	}
+	if patch.Emails != nil {
+		contact.Emails = append([]Email(nil), *patch.Emails...)
+	}
+	if patch.Websites != nil {
+		contact.Websites = append([]Website(nil), *patch.Websites...)
+	}
+	if patch.Messaging != nil {
+		contact.Messaging = append([]MessagingHandle(nil), *patch.Messaging...)
+	}
+	if patch.Tags != nil {
+		contact.Tags = append([]string(nil), *patch.Tags...)
+	}
+	for name, value := range patch.Custom {
+		if value == nil {
+			delete(contact.Custom, name)
+			continue
+		}
+		if contact.Custom == nil {
+			contact.Custom = map[string]string{}
+		}
+		contact.Custom[name] = *value
+	}
+	if len(patch.Custom) > 0 && len(contact.Custom) == 0 {
+		contact.Custom = nil
+	}
+	return contact
+}
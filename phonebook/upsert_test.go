@@ -0,0 +1,110 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_Upsert(t *testing.T) {
+	phoneBook := New()
+
+	id, replaced, err := phoneBook.Upsert(context.Background(), Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"})
+	require.NoError(t, err)
+	require.False(t, replaced)
+	require.NotEmpty(t, id)
+
+	// Any format of an existing number replaces the contact that owns it
+	updated := Contact{Numbers: mobile("0123 456 789"), FirstName: "One", LastName: "Updated"}
+	gotID, replaced, err := phoneBook.Upsert(context.Background(), updated)
+	require.NoError(t, err)
+	require.True(t, replaced)
+	require.Equal(t, id, gotID)
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, "Updated", got.LastName)
+
+	// A contact with an ID replaces the contact with that ID
+	gotID, replaced, err = phoneBook.Upsert(context.Background(), Contact{ID: id, Numbers: mobile("9876543210"), FirstName: "One", LastName: "Moved"})
+	require.NoError(t, err)
+	require.True(t, replaced)
+	require.Equal(t, id, gotID)
+	_, ok = phoneBook.Get("0123456789")
+	require.False(t, ok)
+
+	_, _, err = phoneBook.Upsert(context.Background(), Contact{Numbers: mobile("5432167890"), FirstName: "Two"})
+	require.EqualError(t, err, "last name required")
+	_, _, err = phoneBook.Upsert(context.Background(), Contact{ID: "unknown", Numbers: mobile("5432167890"), FirstName: "Two", LastName: "Two"})
+	require.EqualError(t, err, "contact not found for ID unknown")
+
+	history, err := phoneBook.HistoryByID(id)
+	require.NoError(t, err)
+	require.Equal(t, []EventType{Added, Updated, Updated}, []EventType{history[0].Type, history[1].Type, history[2].Type})
+}
+
+func TestPhoneBook_Patch(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{
+		Numbers:   mobile("0123456789"),
+		FirstName: "One",
+		LastName:  "One",
+		Address:   newAddress("Foo City"),
+		Emails:    []Email{{Type: Personal, Address: "one@example.com"}},
+		Tags:      []string{"suppliers"},
+		Custom:    map[string]string{"employee_id": "1", "team": "sales"},
+	}
+	add(t, phoneBook, &contact)
+
+	lastName := "Updated"
+	team := "support"
+	require.NoError(t, phoneBook.Patch(context.Background(), "0123456789", ContactPatch{
+		LastName: &lastName,
+		Tags:     &[]string{},
+		Custom:   map[string]*string{"team": &team, "employee_id": nil},
+	}))
+
+	// Only the fields in the patch change
+	contact.LastName = "Updated"
+	contact.Tags = nil
+	contact.Custom = map[string]string{"team": "support"}
+	contact.Version = 2
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, contact, got)
+	require.Equal(t, []Contact{contact}, phoneBook.FindByCity("Foo City"))
+	require.Empty(t, phoneBook.FindByTag("suppliers"))
+
+	history, err := phoneBook.History("0123456789")
+	require.NoError(t, err)
+	var fields []string
+	for _, change := range history[1].Changes {
+		fields = append(fields, change.Field)
+	}
+	require.Equal(t, []string{"last_name", "tags", "custom.employee_id", "custom.team"}, fields)
+
+	numbers := mobile("9876543210")
+	require.NoError(t, phoneBook.Patch(context.Background(), "0123456789", ContactPatch{Numbers: &numbers}))
+	_, ok = phoneBook.Get("9876543210")
+	require.True(t, ok)
+
+	empty := ""
+	require.EqualError(t, phoneBook.Patch(context.Background(), "9876543210", ContactPatch{FirstName: &empty}), "first name required")
+	require.EqualError(t, phoneBook.Patch(context.Background(), "0123456789", ContactPatch{}), "contact not found for number 0123456789")
+}
+
+func TestTx_UpsertPatch(t *testing.T) {
+	phoneBook := New()
+	err := phoneBook.Tx(context.Background(), func(tx *Tx) error {
+		_, replaced, err := tx.Upsert(Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"})
+		require.NoError(t, err)
+		require.False(t, replaced)
+		lastName := "Patched"
+		return tx.Patch("0123456789", ContactPatch{LastName: &lastName})
+	})
+	require.NoError(t, err)
+
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, "Patched", got.LastName)
+}
@@ -0,0 +1,63 @@
+package phonebook
+
+import (
+	"context"
+	"fmt"
+)
+
+// VersionConflictError is returned when a contact is changed on the condition
+// that it has an expected version, but it has since been changed by someone
+// else.
+type VersionConflictError struct {
+	ContactID string
+	// Expected is the version the change was made against.
+	Expected uint64
+	// Actual is the current version of the contact.
+	Actual uint64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict for contact %s: expected version %d, found %d", e.ContactID, e.Expected, e.Actual)
+}
+
+// UpdateIfMatch updates the contact that owns the specified number, as Update
+// does, only if the contact's version is the expected version. A
+// VersionConflictError is returned otherwise.
+func (p *PhoneBook) UpdateIfMatch(ctx context.Context, number string, version uint64, update Contact) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, ok := p.contactByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found for number %s", number)
+	}
+	if err := checkVersion(existing, version); err != nil {
+		return err
+	}
+	return p.update(ctx, existing, update)
+}
+
+// DeleteIfMatch deletes the contact that owns the specified number, as Delete
+// does, only if the contact's version is the expected version. A
+// VersionConflictError is returned otherwise. Unlike Delete, an error is
+// returned if the number does not belong to a contact.
+func (p *PhoneBook) DeleteIfMatch(ctx context.Context, number string, version uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, ok := p.contactByNumber(number)
+	if !ok {
+		return fmt.Errorf("contact not found for number %s", number)
+	}
+	if err := checkVersion(existing, version); err != nil {
+		return err
+	}
+	return p.remove(ctx, existing)
+}
+
+func checkVersion(contact *Contact, version uint64) error {
+	if contact.Version != version {
+		return &VersionConflictError{ContactID: contact.ID, Expected: version, Actual: contact.Version}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package phonebook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneBook_version(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Version: 7, Numbers: mobile("0123456789"), FirstName: "One", LastName: "One", Tags: []string{"suppliers"}}
+	id, err := phoneBook.Add(context.Background(), contact)
+	require.NoError(t, err)
+
+	// Versions are assigned by the phone book, and increment on every change
+	version := func() uint64 {
+		got, ok := phoneBook.GetByID(id)
+		require.True(t, ok)
+		return got.Version
+	}
+	require.Equal(t, uint64(1), version())
+
+	contact.LastName = "Updated"
+	require.NoError(t, phoneBook.Update(context.Background(), "0123456789", contact))
+	require.Equal(t, uint64(2), version())
+
+	lastName := "Patched"
+	require.NoError(t, phoneBook.Patch(context.Background(), "0123456789", ContactPatch{LastName: &lastName}))
+	require.Equal(t, uint64(3), version())
+
+	_, err = phoneBook.RenameTag(context.Background(), "suppliers", "vendors")
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), version())
+
+	require.NoError(t, phoneBook.Revert(context.Background(), "0123456789", 1))
+	require.Equal(t, uint64(5), version())
+
+	history, err := phoneBook.HistoryByID(id)
+	require.NoError(t, err)
+	for i, revision := range history {
+		require.Equal(t, uint64(i+1), revision.Contact.Version)
+	}
+}
+
+func TestPhoneBook_UpdateIfMatch(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+
+	// Both agents read version 1
+	first := contact
+	first.LastName = "First"
+	second := contact
+	second.LastName = "Second"
+
+	require.NoError(t, phoneBook.UpdateIfMatch(context.Background(), "0123456789", first.Version, first))
+
+	// The second agent's change is rejected rather than clobbering the first
+	err := phoneBook.UpdateIfMatch(context.Background(), "0123456789", second.Version, second)
+	var conflictErr *VersionConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	require.Equal(t, &VersionConflictError{ContactID: contact.ID, Expected: 1, Actual: 2}, conflictErr)
+	require.EqualError(t, err, "version conflict for contact "+contact.ID+": expected version 1, found 2")
+
+	got, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+	require.Equal(t, "First", got.LastName)
+
+	require.NoError(t, phoneBook.UpdateIfMatch(context.Background(), "0123456789", got.Version, second))
+	require.EqualError(t, phoneBook.UpdateIfMatch(context.Background(), "9876543210", 1, second), "contact not found for number 9876543210")
+}
+
+func TestPhoneBook_DeleteIfMatch(t *testing.T) {
+	phoneBook := New()
+	contact := Contact{Numbers: mobile("0123456789"), FirstName: "One", LastName: "One"}
+	add(t, phoneBook, &contact)
+	updated := contact
+	updated.LastName = "Updated"
+	require.NoError(t, phoneBook.Update(context.Background(), "0123456789", updated))
+
+	var conflictErr *VersionConflictError
+	require.ErrorAs(t, phoneBook.DeleteIfMatch(context.Background(), "0123456789", 1), &conflictErr)
+	_, ok := phoneBook.Get("0123456789")
+	require.True(t, ok)
+
+	require.NoError(t, phoneBook.DeleteIfMatch(context.Background(), "0123456789", 2))
+	_, ok = phoneBook.Get("0123456789")
+	require.False(t, ok)
+	require.EqualError(t, phoneBook.DeleteIfMatch(context.Background(), "0123456789", 2), "contact not found for number 0123456789")
+}